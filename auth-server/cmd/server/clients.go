@@ -0,0 +1,70 @@
+// cmd/server/clients.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"auth-server/internal/config"
+	"auth-server/internal/database"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+// newClientsCmd builds the "server clients" command group, exposing a
+// "create" subcommand since nothing else in this tree populates the
+// clients table the authorization code grant reads from.
+func newClientsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clients",
+		Short: "Manage registered OAuth2 clients",
+	}
+
+	var clientID, name, redirectURI string
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Register a client allowed to use the authorization code grant",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCreateClient(clientID, name, redirectURI)
+		},
+	}
+	createCmd.Flags().StringVar(&clientID, "client-id", "", "unique client identifier (required)")
+	createCmd.Flags().StringVar(&name, "name", "", "human-readable client name (required)")
+	createCmd.Flags().StringVar(&redirectURI, "redirect-uri", "", "the single callback URL this client is allowed to use (required)")
+	createCmd.MarkFlagRequired("client-id")
+	createCmd.MarkFlagRequired("name")
+	createCmd.MarkFlagRequired("redirect-uri")
+	cmd.AddCommand(createCmd)
+
+	return cmd
+}
+
+// runCreateClient opens a database connection from the process
+// configuration and inserts a single clients row.
+func runCreateClient(clientID, name, redirectURI string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	sqlDB, err := cfg.Database.GetDatabaseWithLogging()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer sqlDB.Close()
+
+	db := &database.Database{DB: database.NewLoggingDB(sqlx.NewDb(sqlDB, "postgres"))}
+
+	if err := db.CreateClient(context.Background(), database.Client{
+		ClientID:    clientID,
+		Name:        name,
+		RedirectURI: redirectURI,
+	}); err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	slog.Info("client created", "client_id", clientID)
+	return nil
+}
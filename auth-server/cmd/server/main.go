@@ -2,45 +2,27 @@
 package main
 
 import (
-	"auth-server/internal/api"
-	"auth-server/internal/config"
-	"auth-server/internal/database"
-	"log"
+	"fmt"
 	"os"
 
-	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
 )
 
 func main() {
-	// Set up logging
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	log.Println("Starting auth server...")
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+	rootCmd := &cobra.Command{
+		Use:   "server",
+		Short: "auth-server is the authentication/authorization HTTP service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServer()
+		},
 	}
 
-	// Initialize database connection
-	sqlDB, err := cfg.Database.GetDatabaseWithLogging()
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer sqlDB.Close()
-
-	// Convert sql.DB to sqlx.DB
-	sqlxDB := sqlx.NewDb(sqlDB, "postgres")
-
-	// Create database wrapper
-	db := &database.Database{DB: sqlxDB}
-
-	// Create and configure server
-	server := api.NewServer(db, cfg.Server.GetServerAddr(), cfg.Auth)
-	server.SetupRoutes()
+	rootCmd.AddCommand(newRunCmd())
+	rootCmd.AddCommand(newMigrateCmd())
+	rootCmd.AddCommand(newClientsCmd())
 
-	// Start server
-	if err := server.Start(); err != nil {
-		log.Fatalf("Server error: %v", err)
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
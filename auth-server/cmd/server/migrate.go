@@ -0,0 +1,74 @@
+// cmd/server/migrate.go
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"auth-server/internal/config"
+
+	"github.com/pressly/goose/v3"
+	"github.com/spf13/cobra"
+)
+
+// defaultMigrationsDir is the location of the goose migration files
+// relative to the directory the binary is run from (auth-server/).
+const defaultMigrationsDir = "migrations"
+
+// newMigrateCmd builds the "server migrate" command group, exposing
+// "up"/"down" subcommands that drive goose against the configured
+// database using the SQL files under migrationsDir.
+func newMigrateCmd() *cobra.Command {
+	var migrationsDir string
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Run database migrations",
+	}
+	cmd.PersistentFlags().StringVar(&migrationsDir, "dir", defaultMigrationsDir, "directory containing goose migration files")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigration(migrationsDir, goose.Up)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "down",
+		Short: "Roll back the most recently applied migration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigration(migrationsDir, goose.Down)
+		},
+	})
+
+	return cmd
+}
+
+// runMigration opens a database connection from the process configuration
+// and applies the given goose migration function against migrationsDir.
+func runMigration(migrationsDir string, apply func(db *sql.DB, dir string, opts ...goose.OptionsFunc) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := cfg.Database.GetDatabaseWithLogging()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		return fmt.Errorf("failed to set goose dialect: %w", err)
+	}
+
+	if err := apply(db, migrationsDir); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	slog.Info("migration completed", "dir", migrationsDir)
+	return nil
+}
@@ -0,0 +1,79 @@
+// cmd/server/run.go
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"auth-server/internal/api"
+	"auth-server/internal/config"
+	"auth-server/internal/database"
+	"auth-server/internal/logging"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+// newRunCmd builds the default "server run" subcommand, which starts the
+// HTTP server. It is also aliased as the root command's RunE so that
+// invoking the binary with no subcommand still starts the server.
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Start the auth server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServer()
+		},
+	}
+	return cmd
+}
+
+func runServer() error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Set up structured logging as the process-wide default logger
+	logger := logging.New(cfg.LogFormat, cfg.LogLevel)
+	slog.SetDefault(logger)
+
+	logger.Info("starting auth server")
+
+	// Select the storage backend. "memory" skips the database connection
+	// entirely, so it can run without Postgres; everything else connects
+	// to Postgres as before.
+	var db database.Storage
+	if cfg.StorageBackend == "memory" {
+		logger.Warn("using in-memory storage backend; data will not survive a restart")
+		db = database.NewMemoryStorage()
+	} else {
+		sqlDB, err := cfg.Database.GetDatabaseWithLogging()
+		if err != nil {
+			logger.Error("failed to connect to database", "error", err)
+			os.Exit(1)
+		}
+		defer sqlDB.Close()
+
+		sqlxDB := sqlx.NewDb(sqlDB, "postgres")
+		db = &database.Database{DB: database.NewLoggingDB(sqlxDB)}
+	}
+
+	// Create and configure server
+	server, err := api.NewServer(db, cfg.Server.GetServerAddr(), cfg.Auth, cfg.SessionStore, cfg.ClientRemoteIP, cfg.CORSOrigins, cfg.Admin)
+	if err != nil {
+		logger.Error("failed to create server", "error", err)
+		os.Exit(1)
+	}
+	server.SetupRoutes()
+
+	// Start server
+	if err := server.Start(); err != nil {
+		logger.Error("server error", "error", err)
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+// internal/api/admin.go
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// adminBasicAuth gates next behind HTTP basic auth using constant-time
+// comparisons, so the admin listener doesn't leak credential-length or
+// prefix-match timing information to a scraper on the wrong side of the
+// network. When user or pass is empty, authentication isn't required,
+// matching how middleware.CORS is a no-op when AllowedOrigins is empty.
+func adminBasicAuth(user, pass string, next http.Handler) http.Handler {
+	if user == "" || pass == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, ok := r.BasicAuth()
+		userMatch := subtle.ConstantTimeCompare([]byte(gotUser), []byte(user)) == 1
+		passMatch := subtle.ConstantTimeCompare([]byte(gotPass), []byte(pass)) == 1
+		if !ok || !userMatch || !passMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
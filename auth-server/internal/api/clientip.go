@@ -0,0 +1,108 @@
+// internal/api/clientip.go
+package api
+
+import (
+	"auth-server/internal/config"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPContextKey struct{}
+
+// ClientIPResolver extracts the true client IP from a request that may have
+// passed through one or more trusted reverse proxies, by walking the
+// configured header right-to-left (the order proxies append in) and
+// skipping hops that fall inside a trusted CIDR, stopping at the first
+// untrusted address it finds.
+type ClientIPResolver struct {
+	header  string
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver parses cfg's trusted proxy CIDRs up front so a
+// malformed one fails server startup instead of silently mis-trusting
+// requests later.
+func NewClientIPResolver(cfg config.ClientRemoteIP) (*ClientIPResolver, error) {
+	header := cfg.Header
+	if header == "" {
+		header = "X-Forwarded-For"
+	}
+
+	trusted := make([]netip.Prefix, 0, len(cfg.TrustedProxies))
+	for _, cidr := range cfg.TrustedProxies {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %w", cidr, err)
+		}
+		trusted = append(trusted, prefix)
+	}
+
+	return &ClientIPResolver{header: header, trusted: trusted}, nil
+}
+
+// Middleware resolves the request's real client IP and stashes it in the
+// request context for downstream handlers (see ClientIPFromContext).
+func (c *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := c.resolve(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPContextKey{}, ip)))
+	})
+}
+
+// resolve walks the configured header right-to-left, skipping trusted
+// hops, and falls back to RemoteAddr when no trusted proxies are
+// configured or the header is missing or fully trusted.
+func (c *ClientIPResolver) resolve(r *http.Request) string {
+	if len(c.trusted) == 0 {
+		return remoteHost(r.RemoteAddr)
+	}
+
+	header := r.Header.Get(c.header)
+	if header == "" {
+		return remoteHost(r.RemoteAddr)
+	}
+
+	hops := strings.Split(header, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		candidate := strings.TrimSpace(hops[i])
+		addr, err := netip.ParseAddr(candidate)
+		if err != nil {
+			continue
+		}
+		if !c.isTrusted(addr) {
+			return candidate
+		}
+	}
+
+	return remoteHost(r.RemoteAddr)
+}
+
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost strips the port from a host:port RemoteAddr, returning it
+// unchanged if it isn't one.
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// ClientIPFromContext returns the client IP stashed by
+// ClientIPResolver.Middleware, or "" if that middleware hasn't run.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPContextKey{}).(string)
+	return ip
+}
@@ -0,0 +1,45 @@
+// internal/api/gc.go
+package api
+
+import (
+	"context"
+	"time"
+
+	"auth-server/internal/database"
+	"auth-server/internal/logging"
+)
+
+// gcInterval controls how often expired auth tokens are pruned.
+const gcInterval = 1 * time.Hour
+
+// startGCLoop runs one synchronous garbage collection pass against storage,
+// then a background goroutine that repeats every gcInterval until ctx is
+// cancelled, reporting how many rows it deleted via metrics. storage is
+// accepted as the database.Storage interface rather than *database.Database
+// so any pluggable backend (Postgres, MemoryStorage, ...) can be collected
+// the same way.
+func startGCLoop(ctx context.Context, storage database.Storage, metrics *Metrics) {
+	runGC(ctx, storage, metrics)
+
+	go func() {
+		ticker := time.NewTicker(gcInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runGC(ctx, storage, metrics)
+			}
+		}
+	}()
+}
+
+func runGC(ctx context.Context, storage database.Storage, metrics *Metrics) {
+	deleted, err := storage.GarbageCollect(ctx, time.Now())
+	if err != nil {
+		logging.FromContext(ctx).Error("token garbage collection failed", "error", err)
+		return
+	}
+	metrics.gcTokensDeleted.Add(float64(deleted))
+}
@@ -4,15 +4,23 @@ package api
 import (
 	"auth-server/internal/auth"
 	"auth-server/internal/database"
+	"auth-server/internal/logging"
+	"auth-server/internal/prometheusmetrics"
+	"auth-server/internal/session"
+	"context"
 	"encoding/json"
-	"log"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 type Handler struct {
-	DB        *database.Database
+	Users     database.UserRepository
+	Tokens    database.TokenRepository
 	TokenConf auth.TokenConfig
+	KeyStore  *auth.KeyStore
+	Sessions  session.Store
+	Business  *prometheusmetrics.Metrics
 }
 
 type RegisterRequest struct {
@@ -49,13 +57,14 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	logger := logging.FromContext(ctx)
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("Received registration request for username: %s", req.Username)
+	logger.Info("received registration request", "username", req.Username, "client_ip", ClientIPFromContext(ctx))
 
 	if len(req.Username) < 8 || len(req.Password) < 8 {
 		sendJSONError(w, "Username and password must be at least 8 characters long", http.StatusBadRequest)
@@ -63,9 +72,9 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user already exists
-	existingUser, err := h.DB.GetUserByUsername(ctx, req.Username)
+	existingUser, err := h.Users.GetUserByUsername(ctx, req.Username)
 	if err != nil {
-		log.Printf("Error checking existing user: %v", err)
+		logger.Error("error checking existing user", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -78,19 +87,20 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	// Hash password and create user
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
-		log.Printf("Error hashing password: %v", err)
+		logger.Error("error hashing password", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	_, err = h.DB.CreateUser(ctx, req.Username, hashedPassword)
+	_, err = h.Users.CreateUser(ctx, req.Username, hashedPassword)
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
+		logger.Error("error creating user", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("Successfully registered user: %s", req.Username)
+	logger.Info("successfully registered user", "username", req.Username)
+	h.Business.Registrations.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -99,7 +109,6 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
@@ -107,6 +116,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	logger := logging.FromContext(ctx)
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
@@ -114,25 +124,28 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user from database
-	user, err := h.DB.GetUserByUsername(ctx, req.Username)
+	user, err := h.Users.GetUserByUsername(ctx, req.Username)
 	if err != nil {
-		log.Printf("Error retrieving user: %v", err)
+		logger.Error("error retrieving user", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	if user == nil || !auth.CheckPasswordHash(req.Password, user.HashedPassword) {
+		logger.Error("failed login attempt", "username", req.Username, "client_ip", ClientIPFromContext(ctx))
+		h.Business.LoginsTotal.WithLabelValues(prometheusmetrics.LoginResultFailure).Inc()
 		sendJSONError(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 
 	// Generate new tokens
-	tokens, err := auth.GenerateAuthTokens(h.TokenConf)
+	tokens, err := auth.IssueAuthTokens(h.KeyStore, h.TokenConf, user.ID, user.Username, nil)
 	if err != nil {
-		log.Printf("Error generating tokens: %v", err)
+		logger.Error("error generating tokens", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	h.Business.RecordTokensIssued()
 
 	// Save tokens to database
 	dbToken := database.AuthToken{
@@ -143,13 +156,37 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		ExpiresAt:    tokens.ExpiresAt,
 	}
 
-	if err := h.DB.SaveAuthTokens(ctx, user.ID, dbToken); err != nil {
-		log.Printf("Error saving tokens: %v", err)
+	if err := h.Tokens.SaveAuthTokens(ctx, user.ID, dbToken); err != nil {
+		logger.Error("error saving tokens", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	refreshExpiresAt := time.Now().Add(h.TokenConf.RefreshTokenDuration)
+	if err := h.Tokens.CreateRefreshTokenFamily(ctx, user.ID, tokens.RefreshToken, refreshExpiresAt); err != nil {
+		logger.Error("error creating refresh token family", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("User logged in: %s", req.Username)
+	if err := h.Users.UpdateLastLogin(ctx, user.ID); err != nil {
+		logger.Error("error updating last login", "error", err)
+	}
+
+	// Also start a cookie session so browser clients don't have to manage
+	// the bearer/CSRF tokens themselves.
+	if h.Sessions != nil {
+		sess, err := h.Sessions.New(ctx, user.ID, tokens.CSRFToken, sessionTTL)
+		if err != nil {
+			logger.Error("error creating session", "error", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		setSessionCookie(w, sess)
+	}
+
+	logger.Info("user logged in", "username", req.Username, "client_ip", ClientIPFromContext(ctx))
+	h.Business.LoginsTotal.WithLabelValues(prometheusmetrics.LoginResultSuccess).Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -169,6 +206,7 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	logger := logging.FromContext(ctx)
 	var req RefreshTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
@@ -176,9 +214,9 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user from database
-	user, err := h.DB.GetUserByUsername(ctx, req.Username)
+	user, err := h.Users.GetUserByUsername(ctx, req.Username)
 	if err != nil {
-		log.Printf("Error retrieving user: %v", err)
+		logger.Error("error retrieving user", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -188,29 +226,50 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get stored tokens for user
-	token, _, err := h.DB.GetAuthTokensByRefreshToken(ctx, req.RefreshToken)
+	// Look the presented token up by its rotation record, not just its
+	// presence in auth_tokens, so a token from earlier in the family's
+	// history is still recognized even after later rotations replaced it.
+	record, err := h.Tokens.GetRefreshToken(ctx, req.RefreshToken)
 	if err != nil {
-		log.Printf("Error retrieving tokens: %v", err)
+		logger.Error("error retrieving refresh token", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if record == nil || record.UserID != user.ID || record.Revoked || time.Now().After(record.ExpiresAt) {
+		sendJSONError(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
 
-	if token == nil || token.UserID != user.ID || !auth.ValidateRefreshToken(req.RefreshToken, token.RefreshToken) {
+	// A token that was already rotated being presented again means it was
+	// stolen: revoke the whole family rather than honoring the request.
+	if record.UsedAt != nil {
+		logger.Error("refresh token reuse detected, revoking family", "username", req.Username)
+		if err := h.Tokens.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+			logger.Error("error revoking refresh token family", "error", err)
+		}
+		if err := h.Tokens.DeleteAuthTokens(ctx, user.ID); err != nil {
+			logger.Error("error revoking tokens", "error", err)
+		}
 		sendJSONError(w, "Invalid refresh token", http.StatusUnauthorized)
 		return
 	}
 
-	// Generate new tokens but keep the same refresh token
-	newTokens, err := auth.GenerateAuthTokens(h.TokenConf)
+	// Generate a full new token set, including a brand-new refresh token.
+	newTokens, err := auth.IssueAuthTokens(h.KeyStore, h.TokenConf, user.ID, user.Username, nil)
 	if err != nil {
-		log.Printf("Error generating tokens: %v", err)
+		logger.Error("error generating tokens", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.Business.RecordTokensIssued()
+
+	// Retire the presented token and chain the new one into the same family.
+	refreshExpiresAt := time.Now().Add(h.TokenConf.RefreshTokenDuration)
+	if err := h.Tokens.RotateRefreshToken(ctx, record, newTokens.RefreshToken, refreshExpiresAt); err != nil {
+		logger.Error("error rotating refresh token", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	
-	// Keep the same refresh token
-	newTokens.RefreshToken = token.RefreshToken
 
 	// Save new tokens to database
 	dbToken := database.AuthToken{
@@ -221,13 +280,14 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		ExpiresAt:    newTokens.ExpiresAt,
 	}
 
-	if err := h.DB.SaveAuthTokens(ctx, user.ID, dbToken); err != nil {
-		log.Printf("Error saving tokens: %v", err)
+	if err := h.Tokens.SaveAuthTokens(ctx, user.ID, dbToken); err != nil {
+		logger.Error("error saving tokens", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("Tokens refreshed for user: %s", req.Username)
+	logger.Info("tokens refreshed", "username", req.Username)
+	h.Business.TokenRefreshes.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -247,119 +307,557 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	
+	logger := logging.FromContext(ctx)
+
 	// Get access token from request
 	accessToken := r.Header.Get("X-ACCESS-TOKEN")
-	if accessToken == "" {
+	if accessToken != "" {
+		// Get CSRF token from request
+		csrfToken := r.Header.Get("X-CSRF-TOKEN")
+		if csrfToken == "" {
+			sendJSONError(w, "Missing CSRF token", http.StatusUnauthorized)
+			return
+		}
+
+		_, user, err := h.verifyBearerAccessToken(ctx, accessToken, csrfToken)
+		if err != nil {
+			logger.Error("error retrieving tokens", "error", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			sendJSONError(w, "Invalid access token", http.StatusUnauthorized)
+			return
+		}
+
+		// Delete tokens from database
+		if err := h.Tokens.DeleteAuthTokens(ctx, user.ID); err != nil {
+			logger.Error("error deleting tokens", "error", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		h.endSession(w, r)
+
+		logger.Info("user logged out", "username", user.Username)
+		h.Business.Logouts.Inc()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "User logged out successfully",
+		})
+		return
+	}
+
+	// No bearer token presented; fall back to ending the session cookie so
+	// browser clients can log out without ever holding a bearer token.
+	// Routed through RequireSession rather than re-checking the cookie,
+	// CSRF header, and session lookup inline.
+	if h.Sessions == nil {
 		sendJSONError(w, "Missing access token", http.StatusUnauthorized)
 		return
 	}
+	RequireSession(h.Sessions, h.Users, h.logoutViaSession)(w, r)
+}
 
-	// Get CSRF token from request
-	csrfToken := r.Header.Get("X-CSRF-TOKEN")
-	if csrfToken == "" {
-		sendJSONError(w, "Missing CSRF token", http.StatusUnauthorized)
-		return
+// logoutViaSession is the RequireSession-gated continuation of Logout's
+// cookie path: the session is already authenticated by the time this runs,
+// so it only has to delete it.
+func (h *Handler) logoutViaSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	user, _ := userFromContext(ctx)
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := h.Sessions.Delete(ctx, cookie.Value); err != nil {
+			logger.Error("error deleting session", "error", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
 	}
+	clearSessionCookie(w)
+
+	logger.Info("user logged out via session", "username", user.Username)
+	h.Business.Logouts.Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "User logged out successfully",
+	})
+}
 
-	// Get tokens from database
-	token, user, err := h.DB.GetAuthTokensByAccessToken(ctx, accessToken)
+// endSession best-effort deletes any session cookie presented alongside a
+// bearer-token logout, so a client using both schemes at once doesn't leave
+// a dangling session row behind.
+func (h *Handler) endSession(w http.ResponseWriter, r *http.Request) {
+	if h.Sessions == nil {
+		return
+	}
+	cookie, err := r.Cookie(sessionCookieName)
 	if err != nil {
-		log.Printf("Error retrieving tokens: %v", err)
-		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	h.Sessions.Delete(r.Context(), cookie.Value)
+	clearSessionCookie(w)
+}
+
+// Protected accepts either a valid bearer token or a valid session cookie,
+// so browser clients don't have to manage token storage manually.
+func (h *Handler) Protected(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	accessToken := r.Header.Get("X-ACCESS-TOKEN")
+	if accessToken != "" {
+		csrfToken := r.Header.Get("X-CSRF-TOKEN")
+		if csrfToken == "" {
+			sendJSONError(w, "Missing CSRF token", http.StatusUnauthorized)
+			return
+		}
+
+		_, user, err := h.verifyBearerAccessToken(ctx, accessToken, csrfToken)
+		if err != nil {
+			logger.Error("error retrieving tokens", "error", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			sendJSONError(w, "Invalid access token", http.StatusUnauthorized)
+			return
+		}
+
+		logger.Info("protected resource accessed", "username", user.Username)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Protected resource accessed by user: " + user.Username,
+		})
+		return
+	}
+
+	// No bearer token presented; fall back to the session cookie. Routed
+	// through RequireSession rather than re-checking the cookie, CSRF
+	// header, and session lookup inline.
+	if h.Sessions == nil {
+		sendJSONError(w, "Missing access token", http.StatusUnauthorized)
+		return
+	}
+	RequireSession(h.Sessions, h.Users, h.protectedViaSession)(w, r)
+}
+
+// protectedViaSession is the RequireSession-gated continuation of
+// Protected's cookie path.
+func (h *Handler) protectedViaSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, _ := userFromContext(ctx)
+
+	logging.FromContext(ctx).Info("protected resource accessed via session", "username", user.Username)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Protected resource accessed by user: " + user.Username,
+	})
+}
+
+// verifyBearerAccessToken authenticates accessToken by checking its JWT
+// signature and exp claim against h.KeyStore — downstream services can do
+// the same without ever reaching this server's database. The auth_tokens
+// row is still consulted, but only for what a signature check can't cover:
+// csrfToken, when non-empty, must match the row's CSRF token, and the row's
+// mere presence is what makes Logout/Revoke take effect before the JWT
+// would otherwise expire on its own.
+//
+// A nil user with a nil error means the token is invalid or revoked; a
+// non-nil error means the database lookup itself failed.
+func (h *Handler) verifyBearerAccessToken(ctx context.Context, accessToken, csrfToken string) (*auth.AccessClaims, *database.User, error) {
+	claims, err := auth.ParseAccessToken(h.KeyStore, accessToken)
+	if err != nil {
+		return nil, nil, nil
+	}
+	if time.Now().After(claims.Expiry()) {
+		return nil, nil, nil
+	}
 
+	token, user, err := h.Tokens.GetAuthTokensByAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
 	if token == nil || user == nil {
-		sendJSONError(w, "Invalid access token", http.StatusUnauthorized)
+		return nil, nil, nil
+	}
+	if csrfToken != "" && token.CSRFToken != csrfToken {
+		return nil, nil, nil
+	}
+	return claims, user, nil
+}
+
+func sendJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+}
+
+// authCodeTTL is how long an authorization code issued by Authorize stays redeemable.
+const authCodeTTL = 2 * time.Minute
+
+type AuthorizeRequest struct {
+	Username            string `json:"username"`
+	Password            string `json:"password"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+type AuthorizeResponse struct {
+	Code string `json:"code"`
+}
+
+type TokenRequest struct {
+	GrantType    string `json:"grant_type"`
+	Code         string `json:"code"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// Authorize authenticates the user with the same credential check as Login
+// and, on success, issues a short-lived authorization code bound to the
+// presented PKCE code_challenge (RFC 7636).
+func (h *Handler) Authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	var req AuthorizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Verify CSRF token
-	if token.CSRFToken != csrfToken {
-		sendJSONError(w, "Invalid CSRF token", http.StatusUnauthorized)
+	if req.CodeChallengeMethod != auth.PKCEMethodPlain && req.CodeChallengeMethod != auth.PKCEMethodS256 {
+		sendJSONError(w, "Unsupported code_challenge_method", http.StatusBadRequest)
+		return
+	}
+	if req.CodeChallenge == "" {
+		sendJSONError(w, "Missing code_challenge", http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.Tokens.GetClientByID(ctx, req.ClientID)
+	if err != nil {
+		logger.Error("error retrieving client", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if client == nil {
+		sendJSONError(w, "Unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if req.RedirectURI != client.RedirectURI {
+		sendJSONError(w, "redirect_uri does not match registered client", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Users.GetUserByUsername(ctx, req.Username)
+	if err != nil {
+		logger.Error("error retrieving user", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || !auth.CheckPasswordHash(req.Password, user.HashedPassword) {
+		sendJSONError(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 
-	// Check if token is expired
-	if time.Now().After(token.ExpiresAt) {
-		sendJSONError(w, "Access token expired", http.StatusUnauthorized)
+	code, err := auth.GenerateToken(32)
+	if err != nil {
+		logger.Error("error generating authorization code", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Delete tokens from database
-	if err := h.DB.DeleteAuthTokens(ctx, user.ID); err != nil {
-		log.Printf("Error deleting tokens: %v", err)
+	authCode := database.AuthCode{
+		Code:                code,
+		UserID:              user.ID,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		ExpiresAt:           time.Now().Add(authCodeTTL),
+	}
+	if err := h.Tokens.SaveAuthCode(ctx, authCode); err != nil {
+		logger.Error("error saving authorization code", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("User logged out: %s", user.Username)
+	logger.Info("issued authorization code", "username", req.Username)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "User logged out successfully",
-	})
+	json.NewEncoder(w).Encode(AuthorizeResponse{Code: code})
 }
 
-func (h *Handler) Protected(w http.ResponseWriter, r *http.Request) {
+// Token exchanges an authorization code plus its PKCE code_verifier for the
+// same access/refresh/CSRF token bundle Login hands out.
+func (h *Handler) Token(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
 		return
 	}
 
 	ctx := r.Context()
-	
-	// Get tokens from request headers
-	accessToken := r.Header.Get("X-ACCESS-TOKEN")
-	if accessToken == "" {
-		sendJSONError(w, "Missing access token", http.StatusUnauthorized)
+	logger := logging.FromContext(ctx)
+	var req TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	csrfToken := r.Header.Get("X-CSRF-TOKEN")
-	if csrfToken == "" {
-		sendJSONError(w, "Missing CSRF token", http.StatusUnauthorized)
+	if req.GrantType != "authorization_code" {
+		sendJSONError(w, "Unsupported grant_type", http.StatusBadRequest)
 		return
 	}
 
-	// Get tokens from database
-	token, user, err := h.DB.GetAuthTokensByAccessToken(ctx, accessToken)
+	authCode, err := h.Tokens.GetAuthCode(ctx, req.Code)
 	if err != nil {
-		log.Printf("Error retrieving tokens: %v", err)
+		logger.Error("error retrieving authorization code", "error", err)
 		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if authCode == nil || time.Now().After(authCode.ExpiresAt) {
+		sendJSONError(w, "Invalid or expired authorization code", http.StatusUnauthorized)
+		return
+	}
+	// A code is single-use regardless of outcome, so delete it before validating.
+	if err := h.Tokens.DeleteAuthCode(ctx, req.Code); err != nil {
+		logger.Error("error deleting authorization code", "error", err)
+	}
 
-	if token == nil || user == nil {
-		sendJSONError(w, "Invalid access token", http.StatusUnauthorized)
+	if err := auth.VerifyPKCE(authCode.CodeChallengeMethod, authCode.CodeChallenge, req.CodeVerifier); err != nil {
+		sendJSONError(w, "Invalid code_verifier", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.Users.GetUserByID(ctx, authCode.UserID)
+	if err != nil {
+		logger.Error("error retrieving user", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		sendJSONError(w, "User not found", http.StatusNotFound)
 		return
 	}
 
-	// Verify CSRF token
-	if token.CSRFToken != csrfToken {
-		sendJSONError(w, "Invalid CSRF token", http.StatusUnauthorized)
+	tokens, err := auth.IssueAuthTokens(h.KeyStore, h.TokenConf, user.ID, user.Username, nil)
+	if err != nil {
+		logger.Error("error generating tokens", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	h.Business.RecordTokensIssued()
 
-	// Check if token is expired
-	if time.Now().After(token.ExpiresAt) {
-		sendJSONError(w, "Access token expired", http.StatusUnauthorized)
+	dbToken := database.AuthToken{
+		UserID:       user.ID,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		CSRFToken:    tokens.CSRFToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	}
+	if err := h.Tokens.SaveAuthTokens(ctx, user.ID, dbToken); err != nil {
+		logger.Error("error saving tokens", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	refreshExpiresAt := time.Now().Add(h.TokenConf.RefreshTokenDuration)
+	if err := h.Tokens.CreateRefreshTokenFamily(ctx, user.ID, tokens.RefreshToken, refreshExpiresAt); err != nil {
+		logger.Error("error creating refresh token family", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("Protected resource accessed by user: %s", user.Username)
+	logger.Info("exchanged authorization code for tokens", "username", user.Username)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Protected resource accessed by user: " + user.Username,
+	json.NewEncoder(w).Encode(AuthResponse{
+		Message:      "Token issued successfully",
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		CSRFToken:    tokens.CSRFToken,
+		ExpiresAt:    tokens.ExpiresAt,
 	})
 }
 
-func sendJSONError(w http.ResponseWriter, message string, statusCode int) {
+// UserResponse is the caller's own profile, as returned by GET /user.
+type UserResponse struct {
+	ID          int        `json:"id"`
+	Username    string     `json:"username"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// User returns the authenticated caller's own profile, resolved entirely
+// from the presented access token so clients don't need to separately send
+// their username.
+func (h *Handler) User(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	accessToken := r.Header.Get("X-ACCESS-TOKEN")
+	if accessToken == "" {
+		sendJSONError(w, "Missing access token", http.StatusUnauthorized)
+		return
+	}
+
+	_, user, err := h.verifyBearerAccessToken(ctx, accessToken, "")
+	if err != nil {
+		logger.Error("error retrieving tokens", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		sendJSONError(w, "Invalid access token", http.StatusUnauthorized)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
-}
\ No newline at end of file
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(UserResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		CreatedAt:   user.CreatedAt,
+		LastLoginAt: user.LastLoginAt,
+	})
+}
+
+// RevokeRequest is the RFC 7009 token revocation request body.
+type RevokeRequest struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+}
+
+// Revoke implements RFC 7009: a client can proactively invalidate an access
+// or refresh token before it expires. Per the RFC, an unknown or
+// already-invalid token is not an error, so any non-empty token gets a 200;
+// only a malformed body or a missing token is rejected with 400.
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	var req RevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		sendJSONError(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	if record, err := h.Tokens.GetRefreshToken(ctx, req.Token); err == nil && record != nil {
+		if err := h.Tokens.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+			logger.Error("error revoking refresh token family", "error", err)
+		}
+		if err := h.Tokens.DeleteAuthTokens(ctx, record.UserID); err != nil {
+			logger.Error("error deleting tokens", "error", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if _, user, err := h.Tokens.GetAuthTokensByAccessToken(ctx, req.Token); err == nil && user != nil {
+		if err := h.Tokens.DeleteAuthTokens(ctx, user.ID); err != nil {
+			logger.Error("error deleting tokens", "error", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// IntrospectRequest is the RFC 7662 token introspection request body.
+type IntrospectRequest struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+}
+
+// IntrospectResponse is the RFC 7662 token introspection response. Only
+// Active is populated when the token is not active, per the RFC.
+type IntrospectResponse struct {
+	Active   bool   `json:"active"`
+	Sub      string `json:"sub,omitempty"`
+	Username string `json:"username,omitempty"`
+	Exp      int64  `json:"exp,omitempty"`
+	Iat      int64  `json:"iat,omitempty"`
+	Iss      string `json:"iss,omitempty"`
+	Aud      string `json:"aud,omitempty"`
+}
+
+// Introspect implements RFC 7662: a resource server can ask whether a token
+// it was handed is still valid. It authenticates the same way Protected and
+// User do, so a token invalidated via Logout or Revoke reports inactive even
+// though nothing about the JWT itself has changed.
+func (h *Handler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	var req IntrospectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		sendJSONError(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, user, err := h.verifyBearerAccessToken(ctx, req.Token, "")
+	if err != nil {
+		logger.Error("error retrieving tokens", "error", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if claims == nil || user == nil {
+		json.NewEncoder(w).Encode(IntrospectResponse{Active: false})
+		return
+	}
+
+	json.NewEncoder(w).Encode(IntrospectResponse{
+		Active:   true,
+		Sub:      strconv.Itoa(user.ID),
+		Username: user.Username,
+		Exp:      claims.ExpiresAt,
+		Iat:      claims.IssuedAt,
+		Iss:      auth.Issuer,
+		Aud:      auth.Issuer,
+	})
+}
@@ -0,0 +1,50 @@
+// internal/api/handlers_test.go
+package api
+
+import (
+	"sync"
+	"time"
+
+	"auth-server/internal/auth"
+	"auth-server/internal/database"
+	"auth-server/internal/prometheusmetrics"
+)
+
+var (
+	testMetricsOnce sync.Once
+	testMetrics     *prometheusmetrics.Metrics
+)
+
+// newTestHandler builds a Handler backed by a fresh MemoryStorage and
+// keystore, enough to exercise handlers directly with MemoryStorage rather
+// than a live Postgres. Business metrics are built once per test binary
+// since prometheusmetrics.New registers its collectors against the default
+// Prometheus registry, which panics on a second registration.
+func newTestHandler(t testingTB) *Handler {
+	t.Helper()
+
+	testMetricsOnce.Do(func() {
+		testMetrics = prometheusmetrics.New()
+	})
+
+	ks, err := auth.NewKeyStore(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("failed to build keystore: %v", err)
+	}
+
+	db := database.NewMemoryStorage()
+	return &Handler{
+		Users:     db,
+		Tokens:    db,
+		TokenConf: auth.DefaultTokenConfig,
+		KeyStore:  ks,
+		Business:  testMetrics,
+	}
+}
+
+// testingTB is the subset of *testing.T newTestHandler needs, so it can be
+// called from any test function without importing "testing" into every file.
+type testingTB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
@@ -0,0 +1,97 @@
+// internal/api/health.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"auth-server/internal/database"
+	"auth-server/internal/logging"
+)
+
+// healthCheckInterval controls how often the background probe re-runs.
+const healthCheckInterval = 15 * time.Second
+
+type healthStatus struct {
+	ok        bool
+	latency   time.Duration
+	checkedAt time.Time
+	err       error
+}
+
+// HealthChecker periodically exercises the storage layer and caches the
+// result so GET /healthz can answer instantly instead of hitting Postgres
+// on every request, mirroring dex's newHealthChecker pattern.
+type HealthChecker struct {
+	db database.Storage
+
+	mu     sync.RWMutex
+	status healthStatus
+}
+
+// newHealthChecker runs one synchronous probe, so the first request served
+// gets real data, then starts a background goroutine that re-probes every
+// healthCheckInterval until ctx is cancelled.
+func newHealthChecker(ctx context.Context, db database.Storage) *HealthChecker {
+	hc := &HealthChecker{db: db}
+	hc.probe(ctx)
+
+	go func() {
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				hc.probe(ctx)
+			}
+		}
+	}()
+
+	return hc
+}
+
+func (hc *HealthChecker) probe(ctx context.Context) {
+	start := time.Now()
+	err := hc.db.ProbeHealth(ctx)
+	latency := time.Since(start)
+
+	hc.mu.Lock()
+	hc.status = healthStatus{ok: err == nil, latency: latency, checkedAt: time.Now(), err: err}
+	hc.mu.Unlock()
+}
+
+type healthResponse struct {
+	Status    string    `json:"status"`
+	LatencyMs int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Healthz reports the result of the most recently cached storage probe. It
+// is intentionally registered outside any auth middleware so load balancers
+// and orchestrators can call it without credentials.
+func (hc *HealthChecker) Healthz(w http.ResponseWriter, r *http.Request) {
+	hc.mu.RLock()
+	status := hc.status
+	hc.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !status.ok {
+		logging.FromContext(r.Context()).Error("health probe failing", "error", status.err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: status.err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(healthResponse{
+		Status:    "ok",
+		LatencyMs: status.latency.Milliseconds(),
+		CheckedAt: status.checkedAt,
+	})
+}
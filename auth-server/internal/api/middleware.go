@@ -2,36 +2,54 @@
 package api
 
 import (
-	"log"
+	"auth-server/internal/auth"
+	"auth-server/internal/logging"
+	"auth-server/internal/middleware"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
-// LoggerMiddleware logs request and response details
-func LoggerMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+// LoggerMiddleware creates a per-request *slog.Logger enriched with method,
+// path, remote_addr and the request's ID (see middleware.RequestID),
+// injects it into the request's context, and logs a start/end event pair
+// carrying those fields.
+func LoggerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
 
+		requestID := middleware.RequestIDFromContext(r.Context())
+		if requestID == "" {
+			var err error
+			requestID, err = auth.GenerateToken(16)
+			if err != nil {
+				requestID = "unknown"
+			}
+		}
+
+		logger := logging.FromContext(r.Context()).With(
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+			"request_id", requestID,
+		)
+		ctx := logging.WithLogger(r.Context(), logger)
+		r = r.WithContext(ctx)
+
 		lw := &logResponseWriter{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
 
-		log.Printf(
-			"Request: Method=%s Path=%s",
-			r.Method,
-			r.URL.Path,
-		)
+		logger.Info("request started")
 
-		next(lw, r)
+		next.ServeHTTP(lw, r)
 
-		duration := time.Since(startTime)
-		log.Printf(
-			"Response: Status=%d Duration=%v",
-			lw.statusCode,
-			duration,
+		logger.Info("request completed",
+			slog.Int("status", lw.statusCode),
+			slog.Duration("duration", time.Since(startTime)),
 		)
-	}
+	})
 }
 
 type logResponseWriter struct {
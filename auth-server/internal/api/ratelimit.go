@@ -0,0 +1,124 @@
+// internal/api/ratelimit.go
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// bucketStaleAfter bounds how long an untouched IP's bucket is kept before
+// the background sweep evicts it. Without this, buckets accumulate forever
+// keyed by an attacker-controllable X-Forwarded-For/RemoteAddr string,
+// which is itself a memory-exhaustion vector on the very endpoint meant to
+// guard against abuse.
+const bucketStaleAfter = 10 * time.Minute
+
+// bucketSweepInterval controls how often the stale-bucket sweep runs.
+const bucketSweepInterval = 5 * time.Minute
+
+// IPRateLimiter throttles requests per client IP with a token bucket per
+// address: each address starts with burst tokens and refills one every
+// interval, computed lazily on access. A background sweep evicts buckets
+// that have gone untouched for bucketStaleAfter so the map doesn't grow
+// unbounded for the life of the process.
+type IPRateLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*ipBucket
+	burst    int
+	interval time.Duration
+}
+
+type ipBucket struct {
+	tokens   int
+	lastSeen time.Time
+}
+
+// NewIPRateLimiter allows burst requests immediately per IP, refilling one
+// token every interval, and starts the background sweep that evicts stale
+// buckets until ctx is cancelled.
+func NewIPRateLimiter(ctx context.Context, burst int, interval time.Duration) *IPRateLimiter {
+	l := &IPRateLimiter{
+		buckets:  make(map[string]*ipBucket),
+		burst:    burst,
+		interval: interval,
+	}
+	l.startSweep(ctx)
+	return l
+}
+
+// startSweep runs a background goroutine that evicts stale buckets every
+// bucketSweepInterval until ctx is cancelled. Unlike startGCLoop, it runs
+// no initial synchronous pass; the first sweep happens after one interval.
+func (l *IPRateLimiter) startSweep(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(bucketSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				l.sweep()
+			}
+		}
+	}()
+}
+
+// sweep deletes any bucket untouched for longer than bucketStaleAfter.
+func (l *IPRateLimiter) sweep() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := time.Now().Add(-bucketStaleAfter)
+	for ip, b := range l.buckets {
+		if b.lastSeen.Before(cutoff) {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// Allow reports whether ip may make a request right now, consuming a token
+// if so.
+func (l *IPRateLimiter) Allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		l.buckets[ip] = &ipBucket{tokens: l.burst - 1, lastSeen: time.Now()}
+		return true
+	}
+
+	if refill := int(time.Since(b.lastSeen) / l.interval); refill > 0 {
+		b.tokens += refill
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.lastSeen = time.Now()
+	}
+
+	if b.tokens <= 0 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Middleware rejects requests over the per-IP limit with 429, reading the
+// client IP stashed by ClientIPResolver.Middleware (falling back to
+// RemoteAddr if that middleware wasn't chained in front of it).
+func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIPFromContext(r.Context())
+		if ip == "" {
+			ip = remoteHost(r.RemoteAddr)
+		}
+		if !l.Allow(ip) {
+			sendJSONError(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
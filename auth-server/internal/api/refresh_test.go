@@ -0,0 +1,102 @@
+// internal/api/refresh_test.go
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func doRefresh(h *Handler, username, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(RefreshTokenRequest{Username: username, RefreshToken: refreshToken})
+	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.RefreshToken(rec, req)
+	return rec
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	userID, err := h.Users.CreateUser(ctx, "alice", "hashed")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	const oldToken = "old-refresh-token"
+	if err := h.Tokens.CreateRefreshTokenFamily(ctx, userID, oldToken, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateRefreshTokenFamily: %v", err)
+	}
+
+	rec := doRefresh(h, "alice", oldToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first refresh: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp AuthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.RefreshToken == "" || resp.RefreshToken == oldToken {
+		t.Fatalf("expected a new refresh token, got %q", resp.RefreshToken)
+	}
+
+	record, err := h.Tokens.GetRefreshToken(ctx, oldToken)
+	if err != nil {
+		t.Fatalf("GetRefreshToken: %v", err)
+	}
+	if record == nil || record.UsedAt == nil {
+		t.Fatalf("expected the rotated-out token to be marked used")
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	userID, err := h.Users.CreateUser(ctx, "bob", "hashed")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	const oldToken = "stolen-refresh-token"
+	if err := h.Tokens.CreateRefreshTokenFamily(ctx, userID, oldToken, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateRefreshTokenFamily: %v", err)
+	}
+
+	rec := doRefresh(h, "bob", oldToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("legitimate refresh: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp AuthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	// The attacker replays the same (now-rotated) token.
+	rec = doRefresh(h, "bob", oldToken)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed refresh: expected 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	record, err := h.Tokens.GetRefreshToken(ctx, oldToken)
+	if err != nil {
+		t.Fatalf("GetRefreshToken: %v", err)
+	}
+	if record == nil || !record.Revoked {
+		t.Fatalf("expected the replayed token's family to be revoked")
+	}
+
+	// The legitimate successor token from the first /refresh belongs to the
+	// same family, so revoking the family must also lock it out even though
+	// it was never itself replayed.
+	rec = doRefresh(h, "bob", resp.RefreshToken)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("successor token after family revocation: expected 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
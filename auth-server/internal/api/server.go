@@ -3,30 +3,62 @@ package api
 
 import (
 	"auth-server/internal/auth"
+	"auth-server/internal/config"
 	"auth-server/internal/database"
+	"auth-server/internal/middleware"
+	"auth-server/internal/prometheusmetrics"
+	"auth-server/internal/session"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/gorilla/securecookie"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	db         *database.Database
-	server     *http.Server
-	tokenConf  auth.TokenConfig
-	metrics    *Metrics
+	db            database.Storage
+	server        *http.Server
+	adminServer   *http.Server
+	adminConfig   config.AdminConfig
+	tokenConf     auth.TokenConfig
+	keyStore      *auth.KeyStore
+	metrics       *Metrics
+	healthChecker *HealthChecker
+	sessionStore  session.Store
+	clientIP      *ClientIPResolver
+	loginLimiter  *IPRateLimiter
+	corsOrigins   []string
+	business      *prometheusmetrics.Metrics
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
+
+// loginRateLimitBurst and loginRateLimitInterval bound how often a single
+// client IP may hit /login or /register before getting a 429.
+const (
+	loginRateLimitBurst    = 10
+	loginRateLimitInterval = time.Minute
+)
 type Metrics struct {
     requestsTotal     *prometheus.CounterVec
     requestDuration   *prometheus.HistogramVec
+    requestSize       *prometheus.HistogramVec
+    responseSize      *prometheus.HistogramVec
+    inflightRequests  *prometheus.GaugeVec
     activeConnections prometheus.Gauge
+    gcTokensDeleted   prometheus.Counter
 }
 
 
@@ -47,62 +79,203 @@ func NewMetrics() *Metrics {
             },
             []string{"method", "endpoint"},
         ),
+        requestSize: prometheus.NewHistogramVec(
+            prometheus.HistogramOpts{
+                Name:    "http_request_size_bytes",
+                Help:    "Size of HTTP request bodies in bytes",
+                Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+            },
+            []string{"method", "endpoint"},
+        ),
+        responseSize: prometheus.NewHistogramVec(
+            prometheus.HistogramOpts{
+                Name:    "http_response_size_bytes",
+                Help:    "Size of HTTP response bodies in bytes",
+                Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+            },
+            []string{"method", "endpoint"},
+        ),
+        inflightRequests: prometheus.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "http_inflight_requests",
+                Help: "Number of in-flight HTTP requests",
+            },
+            []string{"method", "endpoint"},
+        ),
         activeConnections: prometheus.NewGauge(
             prometheus.GaugeOpts{
                 Name: "http_active_connections",
                 Help: "Number of active HTTP connections",
             },
         ),
+        gcTokensDeleted: prometheus.NewCounter(
+            prometheus.CounterOpts{
+                Name: "auth_tokens_garbage_collected_total",
+                Help: "Total number of expired auth tokens pruned by the background garbage collector",
+            },
+        ),
     }
-    
+
     // Register metrics with Prometheus
     prometheus.MustRegister(m.requestsTotal)
     prometheus.MustRegister(m.requestDuration)
+    prometheus.MustRegister(m.requestSize)
+    prometheus.MustRegister(m.responseSize)
+    prometheus.MustRegister(m.inflightRequests)
     prometheus.MustRegister(m.activeConnections)
-    
+    prometheus.MustRegister(m.gcTokensDeleted)
+
     return m
 }
 
-// NewServer creates a new server instance
-func NewServer(db *database.Database, addr string, tokenConf auth.TokenConfig) *Server {
+// keyRotationInterval controls how often the server's JWT signing key rotates.
+const keyRotationInterval = 24 * time.Hour
+
+// NewServer creates a new server instance. db is the storage backend
+// selected by config.StorageBackend (see cmd/server/run.go) — *database.Database
+// for Postgres or *database.MemoryStorage for local development/tests.
+// sessionStoreKind selects the session.Store backend: "cookie" for a
+// stateless dev store, anything else (including "") for the Postgres-backed
+// store, which requires db to be *database.Database. clientIPCfg is
+// validated here, so a malformed trusted-proxy CIDR fails server startup
+// rather than the first request. corsOrigins lists the origins allowed to
+// make cross-origin requests; nil disables CORS. adminConf configures the
+// dedicated admin listener that serves /metrics separately from the
+// public server.
+func NewServer(db database.Storage, addr string, tokenConf auth.TokenConfig, sessionStoreKind string, clientIPCfg config.ClientRemoteIP, corsOrigins []string, adminConf config.AdminConfig) (*Server, error) {
+    ctx, cancel := context.WithCancel(context.Background())
+
+    keyStore, err := auth.NewPersistedKeyStore(ctx, db, keyRotationInterval)
+    if err != nil {
+        cancel()
+        return nil, fmt.Errorf("failed to initialize signing key store: %w", err)
+    }
+
+    clientIP, err := NewClientIPResolver(clientIPCfg)
+    if err != nil {
+        cancel()
+        return nil, fmt.Errorf("failed to configure client IP resolver: %w", err)
+    }
+
+    var sessionStore session.Store
+    if sessionStoreKind == "cookie" {
+        // Keys are regenerated every process start, which invalidates all
+        // outstanding sessions on restart; fine for local development,
+        // unsuitable for a real deployment (use "postgres" there).
+        sessionStore = session.NewCookieStore(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32))
+    } else {
+        pgDB, ok := db.(*database.Database)
+        if !ok {
+            cancel()
+            return nil, fmt.Errorf("session store %q requires the postgres storage backend", sessionStoreKind)
+        }
+        sessionStore = session.NewPostgresStore(pgDB)
+    }
+
     return &Server{
-        db:        db,
-        tokenConf: tokenConf,
+        db:           db,
+        tokenConf:    tokenConf,
+        keyStore:     keyStore,
+        sessionStore: sessionStore,
+        clientIP:     clientIP,
+        loginLimiter: NewIPRateLimiter(ctx, loginRateLimitBurst, loginRateLimitInterval),
+        corsOrigins:  corsOrigins,
+        business:     prometheusmetrics.New(),
+        adminConfig:  adminConf,
         server: &http.Server{
             Addr:         addr,
             ReadTimeout:  10 * time.Second,
             WriteTimeout: 10 * time.Second,
             IdleTimeout:  120 * time.Second,
         },
+        adminServer: &http.Server{
+            Addr:         adminConf.Addr,
+            ReadTimeout:  10 * time.Second,
+            WriteTimeout: 10 * time.Second,
+            IdleTimeout:  120 * time.Second,
+        },
         metrics: NewMetrics(),
+        ctx:     ctx,
+        cancel:  cancel,
+    }, nil
+}
+
+// MetricsMiddleware returns a middleware.Decorator that records Prometheus
+// request counts, durations, sizes, and in-flight gauges around next. route
+// is the normalized route label (e.g. the mux pattern the handler was
+// registered under) recorded on every metric instead of the raw, unbounded-
+// cardinality request path.
+func (s *Server) MetricsMiddleware(route string) middleware.Decorator {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            s.metrics.inflightRequests.WithLabelValues(r.Method, route).Inc()
+            defer s.metrics.inflightRequests.WithLabelValues(r.Method, route).Dec()
+
+            s.metrics.activeConnections.Inc()
+            defer s.metrics.activeConnections.Dec()
+
+            start := time.Now()
+            reqSize := countRequestSize(r)
+            wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+            next.ServeHTTP(wrapper, r)
+
+            duration := time.Since(start).Seconds()
+            observeDurationWithExemplar(s.metrics.requestDuration.WithLabelValues(r.Method, route), duration, middleware.SpanIDFromContext(r.Context()))
+            s.metrics.requestSize.WithLabelValues(r.Method, route).Observe(float64(reqSize()))
+            s.metrics.responseSize.WithLabelValues(r.Method, route).Observe(float64(wrapper.bytesWritten))
+            s.metrics.requestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(wrapper.statusCode)).Inc()
+        })
     }
 }
 
-func (s *Server) MetricsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-    return func(w http.ResponseWriter, r *http.Request) {
-        // Increment active connections
-        s.metrics.activeConnections.Inc()
-        defer s.metrics.activeConnections.Dec()
-        
-        // Track request duration
-        start := time.Now()
-        
-        // Create a wrapper to capture the status code
-        wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-        
-        // Call the next handler
-        next(wrapper, r)
-        
-        // Record metrics
-        duration := time.Since(start).Seconds()
-        s.metrics.requestDuration.WithLabelValues(r.Method, r.URL.Path).Observe(duration)
-        s.metrics.requestsTotal.WithLabelValues(r.Method, r.URL.Path, http.StatusText(wrapper.statusCode)).Inc()
+// observeDurationWithExemplar records duration on obs, attaching traceID as
+// an exemplar when both the observer supports it and traceID is non-empty
+// — this lets Grafana jump from a slow latency bucket straight to the
+// trace that produced it. obs is the prometheus.Observer returned by
+// requestDuration.WithLabelValues, which always also implements
+// prometheus.ExemplarObserver; the type assertion exists only to degrade
+// gracefully if that ever stops being true.
+func observeDurationWithExemplar(obs prometheus.Observer, duration float64, traceID string) {
+    exemplarObs, ok := obs.(prometheus.ExemplarObserver)
+    if !ok || traceID == "" {
+        obs.Observe(duration)
+        return
     }
+    exemplarObs.ObserveWithExemplar(duration, prometheus.Labels{"trace_id": traceID})
+}
+
+// countRequestSize returns a function yielding the request body size in
+// bytes once the handler has finished reading it. When Content-Length is
+// known up front it's used directly; otherwise r.Body is wrapped in a
+// counting reader, since a chunked or unspecified-length body isn't known
+// until it's actually been read.
+func countRequestSize(r *http.Request) func() int64 {
+    if r.ContentLength >= 0 {
+        length := r.ContentLength
+        return func() int64 { return length }
+    }
+
+    counter := &countingReadCloser{ReadCloser: r.Body}
+    r.Body = counter
+    return func() int64 { return counter.n }
+}
+
+type countingReadCloser struct {
+    io.ReadCloser
+    n int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+    n, err := c.ReadCloser.Read(p)
+    c.n += int64(n)
+    return n, err
 }
 
 type responseWriter struct {
     http.ResponseWriter
-    statusCode int
+    statusCode   int
+    bytesWritten int64
 }
 
 // WriteHeader captures the status code
@@ -111,32 +284,156 @@ func (rw *responseWriter) WriteHeader(code int) {
     rw.ResponseWriter.WriteHeader(code)
 }
 
+// Write counts bytes written so MetricsMiddleware can observe response size.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+    n, err := rw.ResponseWriter.Write(b)
+    rw.bytesWritten += int64(n)
+    return n, err
+}
+
 // SetupRoutes configures all the routes for the server
 func (s *Server) SetupRoutes() {
     handler := &Handler{
-        DB:        s.db,
+        Users:     s.db,
+        Tokens:    s.db,
         TokenConf: s.tokenConf,
+        KeyStore:  s.keyStore,
+        Sessions:  s.sessionStore,
+        Business:  s.business,
     }
 
+    s.healthChecker = newHealthChecker(s.ctx, s.db)
+    startGCLoop(s.ctx, s.db, s.metrics)
+
     mux := http.NewServeMux()
-    mux.HandleFunc("/register", s.MetricsMiddleware(LoggerMiddleware(handler.Register)))
-    mux.HandleFunc("/login", s.MetricsMiddleware(LoggerMiddleware(handler.Login)))
-    mux.HandleFunc("/refresh", s.MetricsMiddleware(LoggerMiddleware(handler.RefreshToken)))
-    mux.HandleFunc("/logout", s.MetricsMiddleware(LoggerMiddleware(handler.Logout)))
-    mux.HandleFunc("/protected", s.MetricsMiddleware(LoggerMiddleware(handler.Protected)))
-    
-    // Add Prometheus metrics endpoint
-    mux.Handle("/metrics", promhttp.Handler())
+
+    // register builds the standard decorator chain for pattern — recover
+    // first so it catches a panic from anything below it (a panicking
+    // request is logged by Recover but not counted in requestsTotal or
+    // requestDuration), then request ID/tracing context, then metrics (labeled with pattern
+    // itself rather than the raw request path, so it stays low-cardinality)
+    // and the logger, plus any route-specific decorators in extra — and
+    // wires handler onto mux at pattern.
+    register := func(pattern string, handler http.HandlerFunc, extra ...middleware.Decorator) {
+        decorators := append([]middleware.Decorator{
+            middleware.Recover,
+            middleware.RequestID,
+            middleware.Tracing,
+            middleware.CORS(middleware.CORSConfig{AllowedOrigins: s.corsOrigins}),
+            s.MetricsMiddleware(pattern),
+            LoggerMiddleware,
+        }, extra...)
+        middleware.New(decorators...).Handle(mux, pattern, handler)
+    }
+
+    register("/register", handler.Register, s.clientIP.Middleware, s.loginLimiter.Middleware)
+    register("/login", handler.Login, s.clientIP.Middleware, s.loginLimiter.Middleware)
+    register("/refresh", handler.RefreshToken)
+    register("/logout", handler.Logout)
+    register("/protected", handler.Protected)
+    register("/keys", s.Keys)
+    register("/authorize", handler.Authorize, s.clientIP.Middleware, s.loginLimiter.Middleware)
+    register("/token", handler.Token)
+    register("/user", handler.User)
+    register("/revoke", handler.Revoke)
+    register("/token/introspect", handler.Introspect)
+    register("/.well-known/jwks.json", s.Keys)
+    register("/.well-known/openid-configuration", s.OpenIDConfiguration)
+    mux.HandleFunc("/healthz", s.healthChecker.Healthz)
 
     s.server.Handler = mux
+
+    // The admin listener carries operational endpoints like /metrics on a
+    // separate address, so scrape traffic doesn't share the public
+    // listener's timeouts, middleware chain, or exposure. It's optionally
+    // gated behind basic auth when credentials are configured.
+    adminMux := http.NewServeMux()
+    // EnableOpenMetrics switches the exposition format to OpenMetrics,
+    // the only format that can carry the exemplars MetricsMiddleware
+    // attaches to requestDuration observations via ObserveWithExemplar.
+    metricsHandler := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+    adminMux.Handle("/metrics", adminBasicAuth(s.adminConfig.BasicAuthUser, s.adminConfig.BasicAuthPass, metricsHandler))
+    s.adminServer.Handler = adminMux
+}
+
+// Keys serves the JWKS of the server's current and retired signing keys so
+// clients can verify access tokens without calling back into the auth server.
+func (s *Server) Keys(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
+        return
+    }
+
+    maxAge := int(s.keyStore.CacheMaxAge().Seconds())
+    w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(auth.JWKS(s.keyStore))
+}
+
+// openIDConfiguration is the subset of the OIDC discovery document
+// (https://openid.net/specs/openid-connect-discovery-1_0.html) this server
+// actually backs.
+type openIDConfiguration struct {
+    Issuer                           string   `json:"issuer"`
+    JWKSURI                          string   `json:"jwks_uri"`
+    AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+    TokenEndpoint                    string   `json:"token_endpoint"`
+    IntrospectionEndpoint            string   `json:"introspection_endpoint"`
+    RevocationEndpoint               string   `json:"revocation_endpoint"`
+    UserinfoEndpoint                 string   `json:"userinfo_endpoint"`
+    ResponseTypesSupported           []string `json:"response_types_supported"`
+    SubjectTypesSupported            []string `json:"subject_types_supported"`
+    IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+    CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+}
+
+// OpenIDConfiguration serves the OIDC discovery document so clients can
+// locate this server's JWKS, authorize, token, and related endpoints
+// without hard-coding them.
+func (s *Server) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+        sendJSONError(w, "Invalid method", http.StatusMethodNotAllowed)
+        return
+    }
+
+    base := issuerURL(r)
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(openIDConfiguration{
+        Issuer:                           base,
+        JWKSURI:                          base + "/.well-known/jwks.json",
+        AuthorizationEndpoint:            base + "/authorize",
+        TokenEndpoint:                    base + "/token",
+        IntrospectionEndpoint:            base + "/token/introspect",
+        RevocationEndpoint:               base + "/revoke",
+        UserinfoEndpoint:                 base + "/user",
+        ResponseTypesSupported:           []string{"code"},
+        SubjectTypesSupported:            []string{"public"},
+        IDTokenSigningAlgValuesSupported: []string{"RS256"},
+        CodeChallengeMethodsSupported:    []string{auth.PKCEMethodPlain, auth.PKCEMethodS256},
+    })
+}
+
+// issuerURL derives this server's own base URL from the incoming request,
+// since it isn't otherwise configured with its externally-visible address.
+func issuerURL(r *http.Request) string {
+    scheme := "http"
+    if r.TLS != nil {
+        scheme = "https"
+    }
+    return scheme + "://" + r.Host
 }
 
 // Start begins the server and handles graceful shutdown
 func (s *Server) Start() error {
-    // Channel for server errors
-    errChan := make(chan error, 1)
+    // The active-user gauge refreshes in the background for as long as the
+    // server runs, stopping when s.cancel() fires during shutdown below.
+    s.business.StartActiveUserLoop(s.ctx, s.db)
+
+    // Channel for server errors, shared between the public and admin
+    // listeners so either one failing unblocks shutdown of both.
+    errChan := make(chan error, 2)
 
-    // Start server in a goroutine
+    // Start the public server in a goroutine
     go func() {
         log.Printf("Server starting on %s...", s.server.Addr)
         if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -144,6 +441,21 @@ func (s *Server) Start() error {
         }
     }()
 
+    // Start the admin server in a goroutine, over TLS when a cert/key pair
+    // is configured.
+    go func() {
+        log.Printf("Admin server starting on %s...", s.adminServer.Addr)
+        var err error
+        if s.adminConfig.TLSCert != "" && s.adminConfig.TLSKey != "" {
+            err = s.adminServer.ListenAndServeTLS(s.adminConfig.TLSCert, s.adminConfig.TLSKey)
+        } else {
+            err = s.adminServer.ListenAndServe()
+        }
+        if err != nil && err != http.ErrServerClosed {
+            errChan <- err
+        }
+    }()
+
     // Channel for OS signals
     stop := make(chan os.Signal, 1)
     signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -154,11 +466,22 @@ func (s *Server) Start() error {
         return err
     case <-stop:
         log.Println("Shutting down server...")
+        s.cancel()
+
         ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
         defer cancel()
 
+        // Both listeners share the same 10s grace window; record the
+        // first error but still attempt to shut down the second.
+        var shutdownErr error
         if err := s.server.Shutdown(ctx); err != nil {
-            return err
+            shutdownErr = err
+        }
+        if err := s.adminServer.Shutdown(ctx); err != nil && shutdownErr == nil {
+            shutdownErr = err
+        }
+        if shutdownErr != nil {
+            return shutdownErr
         }
         log.Println("Server gracefully stopped")
     }
@@ -0,0 +1,105 @@
+// internal/api/session.go
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"auth-server/internal/database"
+	"auth-server/internal/logging"
+	"auth-server/internal/session"
+)
+
+// sessionCookieName is the HTTP cookie clients send the session id back in.
+const sessionCookieName = "session"
+
+// sessionTTL is how long a cookie session stays valid. It is independent of
+// the access token lifetime since browser sessions are typically longer-lived.
+const sessionTTL = 24 * time.Hour
+
+type contextKey int
+
+const userContextKey contextKey = 0
+
+// setSessionCookie writes sess.ID as the session cookie.
+func setSessionCookie(w http.ResponseWriter, sess *session.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sess.ID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  sess.ExpiresAt,
+	})
+}
+
+// clearSessionCookie expires the session cookie immediately.
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// userFromContext returns the user RequireSession attached to the request
+// context, if any.
+func userFromContext(ctx context.Context) (*database.User, bool) {
+	user, ok := ctx.Value(userContextKey).(*database.User)
+	return user, ok
+}
+
+// RequireSession authenticates a request purely via its session cookie and
+// the double-submit X-CSRF-TOKEN header, attaching the resolved user to the
+// request context for downstream handlers. Routes that must also accept a
+// bearer token (like Protected and Logout) check the bearer token
+// themselves and hand only their cookie path to this middleware.
+func RequireSession(store session.Store, users database.UserRepository, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx)
+
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil {
+			sendJSONError(w, "Missing session cookie", http.StatusUnauthorized)
+			return
+		}
+
+		sess, err := store.Get(ctx, cookie.Value)
+		if err != nil {
+			logger.Error("error loading session", "error", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if sess == nil || time.Now().After(sess.ExpiresAt) {
+			sendJSONError(w, "Invalid or expired session", http.StatusUnauthorized)
+			return
+		}
+
+		csrfToken := r.Header.Get("X-CSRF-TOKEN")
+		if subtle.ConstantTimeCompare([]byte(csrfToken), []byte(sess.CSRFToken)) != 1 {
+			sendJSONError(w, "Invalid CSRF token", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := users.GetUserByID(ctx, sess.UserID)
+		if err != nil {
+			logger.Error("error retrieving user", "error", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			sendJSONError(w, "Invalid session", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(ctx, userContextKey, user)))
+	}
+}
@@ -0,0 +1,102 @@
+// internal/api/token_test.go
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"auth-server/internal/auth"
+	"auth-server/internal/database"
+)
+
+func doTokenExchange(h *Handler, code, verifier string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		CodeVerifier: verifier,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.Token(rec, req)
+	return rec
+}
+
+func TestTokenExchangePKCES256(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	userID, err := h.Users.CreateUser(ctx, "carol", "hashed")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	const verifier = "a-sufficiently-long-code-verifier-string"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if err := h.Tokens.SaveAuthCode(ctx, database.AuthCode{
+		Code:                "auth-code-s256",
+		UserID:              userID,
+		ClientID:            "client-1",
+		RedirectURI:         "https://example.com/callback",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: auth.PKCEMethodS256,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("SaveAuthCode: %v", err)
+	}
+
+	rec := doTokenExchange(h, "auth-code-s256", verifier)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp AuthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Fatalf("expected tokens to be issued, got %+v", resp)
+	}
+
+	// The code is single-use: presenting it again must fail even with the
+	// correct verifier.
+	rec = doTokenExchange(h, "auth-code-s256", verifier)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed code: expected 401, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTokenExchangeRejectsWrongVerifier(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	userID, err := h.Users.CreateUser(ctx, "dave", "hashed")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if err := h.Tokens.SaveAuthCode(ctx, database.AuthCode{
+		Code:                "auth-code-plain",
+		UserID:              userID,
+		ClientID:            "client-1",
+		RedirectURI:         "https://example.com/callback",
+		CodeChallenge:       "expected-verifier",
+		CodeChallengeMethod: auth.PKCEMethodPlain,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("SaveAuthCode: %v", err)
+	}
+
+	rec := doTokenExchange(h, "auth-code-plain", "wrong-verifier")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for mismatched verifier, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,239 @@
+// internal/auth/keystore.go
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// minJWKSCacheAge is the floor we clamp the JWKS Cache-Control max-age to, so a
+// key that is about to rotate is never advertised with a near-zero or negative TTL.
+const minJWKSCacheAge = 2 * time.Minute
+
+// SigningKey is a single RSA key pair used to sign access tokens, tagged with
+// the `kid` clients use to pick the matching public key out of the JWKS.
+type SigningKey struct {
+	Kid          string
+	NotBefore    time.Time
+	NextRotation time.Time
+
+	private *rsa.PrivateKey
+}
+
+// PublicKey returns the RSA public half of the key pair.
+func (k *SigningKey) PublicKey() *rsa.PublicKey {
+	return &k.private.PublicKey
+}
+
+// PersistedKey is a signing key as stored by a KeyPersister, PEM-encoded so
+// it survives outside the process (e.g. a row in the signing_keys table).
+type PersistedKey struct {
+	Kid          string
+	PrivateKeyPEM string
+	NotBefore    time.Time
+	NextRotation time.Time
+}
+
+// KeyPersister lets a KeyStore survive process restarts by saving newly
+// rotated keys and reloading them at startup. It is satisfied by
+// *database.Database; the auth package stays free of any database import.
+type KeyPersister interface {
+	SaveSigningKey(ctx context.Context, key PersistedKey) error
+	LoadSigningKeys(ctx context.Context) ([]PersistedKey, error)
+}
+
+// KeyStore lazily generates and rotates the RSA key pair used to sign access
+// tokens. Retired keys are kept for the life of the KeyStore so tokens
+// signed with them still verify against the JWKS; nothing prunes them.
+type KeyStore struct {
+	mu               sync.RWMutex
+	rotationInterval time.Duration
+	current          *SigningKey
+	previous         []*SigningKey
+
+	persister KeyPersister
+}
+
+// NewKeyStore creates a KeyStore whose signing key rotates every
+// rotationInterval, generating the first key immediately. Keys generated
+// this way live only as long as the process; use NewPersistedKeyStore to
+// survive restarts.
+func NewKeyStore(rotationInterval time.Duration) (*KeyStore, error) {
+	ks := &KeyStore{rotationInterval: rotationInterval}
+	if err := ks.rotate(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// NewPersistedKeyStore builds a KeyStore backed by persister: existing keys
+// are loaded from it at startup (newest becomes current, the rest retired),
+// and every newly rotated key is saved back through it. If persister has no
+// keys yet, a first key is generated and persisted immediately.
+func NewPersistedKeyStore(ctx context.Context, persister KeyPersister, rotationInterval time.Duration) (*KeyStore, error) {
+	ks := &KeyStore{rotationInterval: rotationInterval, persister: persister}
+
+	persisted, err := persister.LoadSigningKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	for _, p := range persisted {
+		key, err := decodeSigningKey(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode persisted signing key %s: %w", p.Kid, err)
+		}
+		if ks.current == nil || key.NotBefore.After(ks.current.NotBefore) {
+			if ks.current != nil {
+				ks.previous = append(ks.previous, ks.current)
+			}
+			ks.current = key
+		} else {
+			ks.previous = append(ks.previous, key)
+		}
+	}
+
+	if ks.current == nil {
+		if err := ks.rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, nil
+}
+
+func (ks *KeyStore) rotate() error {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	now := time.Now()
+	next := &SigningKey{
+		Kid:          fmt.Sprintf("%d", now.UnixNano()),
+		NotBefore:    now,
+		NextRotation: now.Add(ks.rotationInterval),
+		private:      private,
+	}
+
+	if ks.persister != nil {
+		persisted, err := encodeSigningKey(next)
+		if err != nil {
+			return fmt.Errorf("failed to encode signing key: %w", err)
+		}
+		if err := ks.persister.SaveSigningKey(context.Background(), persisted); err != nil {
+			return fmt.Errorf("failed to persist signing key: %w", err)
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.current != nil {
+		ks.previous = append(ks.previous, ks.current)
+	}
+	ks.current = next
+	return nil
+}
+
+// encodeSigningKey PEM-encodes key's private key (PKCS#1) for storage.
+func encodeSigningKey(key *SigningKey) (PersistedKey, error) {
+	der := x509.MarshalPKCS1PrivateKey(key.private)
+	block := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der})
+	return PersistedKey{
+		Kid:           key.Kid,
+		PrivateKeyPEM: string(block),
+		NotBefore:     key.NotBefore,
+		NextRotation:  key.NextRotation,
+	}, nil
+}
+
+// decodeSigningKey reverses encodeSigningKey.
+func decodeSigningKey(p PersistedKey) (*SigningKey, error) {
+	block, _ := pem.Decode([]byte(p.PrivateKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM block for key %s", p.Kid)
+	}
+	private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return &SigningKey{
+		Kid:          p.Kid,
+		NotBefore:    p.NotBefore,
+		NextRotation: p.NextRotation,
+		private:      private,
+	}, nil
+}
+
+// Current returns the active signing key, rotating first if the previous one
+// is past its next-rotation time.
+func (ks *KeyStore) Current() (*SigningKey, error) {
+	ks.mu.RLock()
+	cur := ks.current
+	ks.mu.RUnlock()
+
+	if cur == nil || time.Now().After(cur.NextRotation) {
+		if err := ks.rotate(); err != nil {
+			return nil, err
+		}
+		ks.mu.RLock()
+		cur = ks.current
+		ks.mu.RUnlock()
+	}
+	return cur, nil
+}
+
+// Lookup finds the signing key (current or retired) matching kid, so a token
+// signed before the most recent rotation can still be verified.
+func (ks *KeyStore) Lookup(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.current != nil && ks.current.Kid == kid {
+		return ks.current, true
+	}
+	for _, k := range ks.previous {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// CacheMaxAge returns how long a client may cache the JWKS response: the time
+// remaining until the current key's next rotation, clamped to a minimum of
+// two minutes so a near-due rotation doesn't announce an unhelpfully tiny TTL.
+func (ks *KeyStore) CacheMaxAge() time.Duration {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.current == nil {
+		return minJWKSCacheAge
+	}
+	if remaining := time.Until(ks.current.NextRotation); remaining > minJWKSCacheAge {
+		return remaining
+	}
+	return minJWKSCacheAge
+}
+
+// Keys returns the current key plus any retired keys still held for
+// verification, newest first.
+func (ks *KeyStore) Keys() []*SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(ks.previous)+1)
+	if ks.current != nil {
+		keys = append(keys, ks.current)
+	}
+	for i := len(ks.previous) - 1; i >= 0; i-- {
+		keys = append(keys, ks.previous[i])
+	}
+	return keys
+}
@@ -0,0 +1,37 @@
+// internal/auth/pkce.go
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+)
+
+// PKCE methods supported by the /authorize and /token endpoints, per RFC 7636.
+const (
+	PKCEMethodPlain = "plain"
+	PKCEMethodS256  = "S256"
+)
+
+// VerifyPKCE checks verifier against the challenge stored at /authorize time
+// using the given method, returning an error for an unknown method or a
+// mismatched verifier.
+func VerifyPKCE(method, challenge, verifier string) error {
+	switch method {
+	case PKCEMethodPlain:
+		if subtle.ConstantTimeCompare([]byte(verifier), []byte(challenge)) != 1 {
+			return fmt.Errorf("code_verifier does not match code_challenge")
+		}
+		return nil
+	case PKCEMethodS256:
+		sum := sha256.Sum256([]byte(verifier))
+		computed := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) != 1 {
+			return fmt.Errorf("code_verifier does not match code_challenge")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported code_challenge_method %q", method)
+	}
+}
@@ -3,17 +3,46 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
 )
 
+// Issuer is the `iss`/`aud` value stamped on every access token this server mints.
+const Issuer = "auth-server"
+
 // TokenConfig contains configuration for token generation
 type TokenConfig struct {
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
 }
 
+// AccessClaims is the payload of a signed access token JWT.
+type AccessClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  string   `json:"aud"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	ID        string   `json:"jti"`
+	Username  string   `json:"username"`
+	Scopes    []string `json:"scopes,omitempty"`
+}
+
+// Expiry returns the claim's expiration as a time.Time for callers that still
+// want to reason about expiry the way they did with the opaque token's ExpiresAt.
+func (c AccessClaims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
 // DefaultTokenConfig provides sensible defaults
 var DefaultTokenConfig = TokenConfig{
 	AccessTokenDuration:  15 * time.Minute,
@@ -38,9 +67,11 @@ func GenerateToken(length int) (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-// GenerateAuthTokens creates a new set of authentication tokens
-func GenerateAuthTokens(config TokenConfig) (Tokens, error) {
-	accessToken, err := GenerateToken(32)
+// IssueAuthTokens creates a new set of authentication tokens for userID: a
+// signed JWT access token plus opaque refresh and CSRF tokens. This replaces
+// the old GenerateAuthTokens, which handed out an opaque access token too.
+func IssueAuthTokens(ks *KeyStore, config TokenConfig, userID int, username string, scopes []string) (Tokens, error) {
+	accessToken, err := IssueAccessToken(ks, userID, username, scopes, config)
 	if err != nil {
 		return Tokens{}, err
 	}
@@ -66,4 +97,106 @@ func GenerateAuthTokens(config TokenConfig) (Tokens, error) {
 // ValidateRefreshToken compares refresh tokens using constant-time comparison
 func ValidateRefreshToken(providedToken, storedToken string) bool {
 	return subtle.ConstantTimeCompare([]byte(providedToken), []byte(storedToken)) == 1
+}
+
+// HashToken returns the hex-encoded SHA-256 hash of token. Refresh tokens
+// are recorded at rest by this hash rather than the raw, bearer-equivalent
+// value, e.g. in the refresh_tokens table used for rotation and reuse
+// detection.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAccessToken mints an RS256-signed JWT access token for userID, signed
+// by the keystore's current key. Refresh and CSRF tokens stay opaque; only
+// the access token moves to a verifiable, stateless format.
+func IssueAccessToken(ks *KeyStore, userID int, username string, scopes []string, config TokenConfig) (string, error) {
+	key, err := ks.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get signing key: %w", err)
+	}
+
+	jti, err := GenerateToken(16)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate jti: %w", err)
+	}
+
+	now := time.Now()
+	claims := AccessClaims{
+		Issuer:    Issuer,
+		Subject:   strconv.Itoa(userID),
+		Audience:  Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(config.AccessTokenDuration).Unix(),
+		ID:        jti,
+		Username:  username,
+		Scopes:    scopes,
+	}
+
+	opts := (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", key.Kid)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key.private}, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create signer: %w", err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return jws.CompactSerialize()
+}
+
+// ParseAccessToken verifies the JWT's signature against the key named by its
+// `kid` header and returns the validated claims. It does not check exp;
+// callers should compare AccessClaims.Expiry() against time.Now() themselves.
+func ParseAccessToken(ks *KeyStore, token string) (*AccessClaims, error) {
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed access token: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("unexpected number of signatures")
+	}
+
+	kid := jws.Signatures[0].Header.KeyID
+	key, ok := ks.Lookup(kid)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	payload, err := jws.Verify(key.PublicKey())
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token signature: %w", err)
+	}
+
+	var claims AccessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse claims: %w", err)
+	}
+	if claims.Issuer != Issuer || claims.Audience != Issuer {
+		return nil, errors.New("unexpected issuer or audience")
+	}
+	return &claims, nil
+}
+
+// JWKS returns the keystore's public keys as a JSON Web Key Set.
+func JWKS(ks *KeyStore) jose.JSONWebKeySet {
+	keys := ks.Keys()
+	jwks := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(keys))}
+	for _, k := range keys {
+		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
+			Key:       k.PublicKey(),
+			KeyID:     k.Kid,
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		})
+	}
+	return jwks
 }
\ No newline at end of file
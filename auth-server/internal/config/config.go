@@ -4,11 +4,14 @@ package config
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"auth-server/internal/auth"
+	"auth-server/internal/logging"
 	"database/sql"
 
 	"github.com/joho/godotenv"
@@ -17,9 +20,41 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     auth.TokenConfig
+	Server         ServerConfig
+	Database       DatabaseConfig
+	Auth           auth.TokenConfig
+	LogFormat      string
+	LogLevel       slog.Level
+	SessionStore   string
+	ClientRemoteIP ClientRemoteIP
+	CORSOrigins    []string
+	Admin          AdminConfig
+	StorageBackend string
+}
+
+// AdminConfig configures the dedicated admin listener that serves
+// operational endpoints like /metrics on their own http.Server, so scrape
+// traffic doesn't share timeouts, middleware, or the public listener.
+// BasicAuthUser/BasicAuthPass are optional; when either is empty, the
+// admin listener serves unauthenticated. TLSCert/TLSKey are optional
+// paths to a certificate/key pair; when either is empty, the admin
+// listener serves plaintext HTTP.
+type AdminConfig struct {
+	Addr          string
+	BasicAuthUser string
+	BasicAuthPass string
+	TLSCert       string
+	TLSKey        string
+}
+
+// ClientRemoteIP configures how the true client IP is resolved from a
+// request that may have passed through trusted reverse proxies: Header is
+// walked right-to-left, skipping hops inside a TrustedProxies CIDR. Leaving
+// TrustedProxies empty disables header parsing entirely, falling back to
+// the request's RemoteAddr.
+type ClientRemoteIP struct {
+	Header         string
+	TrustedProxies []string
 }
 
 // ServerConfig holds server-related configuration
@@ -62,6 +97,55 @@ func Load() (*Config, error) {
         return nil, fmt.Errorf("invalid refresh token duration: %w", err)
     }
     
+    // Logging config
+    logFormat := getEnv("LOG_FORMAT", "json")
+    logLevel := logging.ParseLevel(getEnv("LOG_LEVEL", "info"))
+
+    // Session store: "postgres" (default, durable) or "cookie" (stateless,
+    // for local development).
+    sessionStore := getEnv("SESSION_STORE", "postgres")
+
+    // Storage backend: "postgres" (default, durable) or "memory" (in-process,
+    // for local development or tests without a live database). SESSION_STORE
+    // must be "cookie" when this is "memory", since the Postgres-backed
+    // session store needs a real database connection.
+    storageBackend := getEnv("STORAGE_BACKEND", "postgres")
+
+    // Trusted-proxy client IP resolution: empty CLIENT_IP_TRUSTED_PROXIES
+    // disables it, so a deployment with no reverse proxy in front of it
+    // doesn't need to configure anything.
+    clientIPHeader := getEnv("CLIENT_IP_HEADER", "X-Forwarded-For")
+    var trustedProxies []string
+    if raw := getEnv("CLIENT_IP_TRUSTED_PROXIES", ""); raw != "" {
+        for _, cidr := range strings.Split(raw, ",") {
+            if cidr = strings.TrimSpace(cidr); cidr != "" {
+                trustedProxies = append(trustedProxies, cidr)
+            }
+        }
+    }
+
+    // CORS: empty CORS_ALLOWED_ORIGINS disables cross-origin responses
+    // entirely, so a deployment with no browser-based client doesn't need
+    // to configure anything.
+    var corsOrigins []string
+    if raw := getEnv("CORS_ALLOWED_ORIGINS", ""); raw != "" {
+        for _, origin := range strings.Split(raw, ",") {
+            if origin = strings.TrimSpace(origin); origin != "" {
+                corsOrigins = append(corsOrigins, origin)
+            }
+        }
+    }
+
+    // Admin listener: serves /metrics and other operational endpoints on
+    // their own address, separate from the public server. Basic auth and
+    // TLS are both optional and independently enabled by setting both of
+    // their respective env vars.
+    adminAddr := getEnv("ADMIN_ADDR", "localhost:9091")
+    adminBasicAuthUser := getEnv("ADMIN_BASIC_AUTH_USER", "")
+    adminBasicAuthPass := getEnv("ADMIN_BASIC_AUTH_PASS", "")
+    adminTLSCert := getEnv("ADMIN_TLS_CERT", "")
+    adminTLSKey := getEnv("ADMIN_TLS_KEY", "")
+
     return &Config{
         Server: ServerConfig{
             Host: serverHost,
@@ -74,80 +158,52 @@ func Load() (*Config, error) {
             AccessTokenDuration: accessTokenDuration,
             RefreshTokenDuration: refreshTokenDuration,
         },
+        LogFormat:    logFormat,
+        LogLevel:     logLevel,
+        SessionStore: sessionStore,
+        ClientRemoteIP: ClientRemoteIP{
+            Header:         clientIPHeader,
+            TrustedProxies: trustedProxies,
+        },
+        CORSOrigins: corsOrigins,
+        Admin: AdminConfig{
+            Addr:          adminAddr,
+            BasicAuthUser: adminBasicAuthUser,
+            BasicAuthPass: adminBasicAuthPass,
+            TLSCert:       adminTLSCert,
+            TLSKey:        adminTLSKey,
+        },
+        StorageBackend: storageBackend,
     }, nil
 }
 
 
-// GetDatabaseURL returns the database connection string
-func (c *DatabaseConfig) GetDatabaseURL()  string {
+// GetDatabaseWithLogging opens the database connection. Query-level logging
+// is handled by database.LoggingDB, which wraps the resulting *sql.DB once
+// it's promoted to an *sqlx.DB, rather than by a custom sql.Driver here.
+func (c *DatabaseConfig) GetDatabaseWithLogging() (*sql.DB, error) {
     log.Printf("Attempting to connect to database")
-    
-    db, err := sql.Open("postgres", c.URL)
-    if err != nil {
-        log.Printf("Error opening database connection: %v", err)
-        return c.URL
-    }
-    defer db.Close()
 
-    var result int
-    err = db.QueryRow("SELECT 1+1").Scan(&result)
-    if err != nil {
-        log.Printf("Database connection test failed: %v", err)
-        return c.URL
-    }
-
-    if result == 2 {
-        log.Printf("Database connection test successful")
-    } else {
-        log.Printf("Database connection test returned unexpected result: %d", result)
-    }
-
-    return c.URL
-}
-
-// GetDatabaseWithLogging returns a database connection with query logging enabled
-func (c *DatabaseConfig) GetDatabaseWithLogging() (*sql.DB, error) {
-    log.Printf("Attempting to connect to database with query logging")
-    
-    // Open database connection
     db, err := sql.Open("postgres", c.URL)
     if err != nil {
         log.Printf("Error opening database connection: %v", err)
         return nil, err
     }
-    
-    // Test connection
+
     var result int
-    err = db.QueryRow("SELECT 1+1").Scan(&result)
-    if err != nil {
+    if err := db.QueryRow("SELECT 1+1").Scan(&result); err != nil {
         log.Printf("Database connection test failed: %v", err)
         db.Close()
         return nil, err
     }
-    
-    if result == 2 {
-        log.Printf("Database connection test successful")
-    } else {
+
+    if result != 2 {
         log.Printf("Database connection test returned unexpected result: %d", result)
     }
-    
-    // Enable query logging by setting a driver-specific logger
-    // For PostgreSQL, we can use a custom driver
-    driver := &LoggingDriver{parent: db.Driver()}
-    sql.Register("postgres-logging", driver)
-    
-    // Open a new connection with the logging driver
-    dbWithLogging, err := sql.Open("postgres-logging", c.URL)
-    if err != nil {
-        log.Printf("Error opening logging database connection: %v", err)
-        db.Close()
-        return nil, err
-    }
-    
-    db.Close() // Close the original connection
-    
-    return dbWithLogging, nil
+
+    return db, nil
 }
+
 // GetServerAddr returns the formatted server address
 func (c *ServerConfig) GetServerAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
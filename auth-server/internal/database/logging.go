@@ -0,0 +1,56 @@
+// internal/database/logging.go
+package database
+
+import (
+	"auth-server/internal/logging"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LoggingDB wraps an *sqlx.DB so queries go through slog instead of the
+// old LoggingDriver, which re-registered a whole sql.Driver just to print
+// queries. Only ExecContext, GetContext and SelectContext are logged; every
+// other method, including statements run inside a transaction begun with
+// BeginTxx, is the embedded *sqlx.DB's own and is not logged.
+type LoggingDB struct {
+	*sqlx.DB
+}
+
+// NewLoggingDB wraps db so its ExecContext, GetContext and SelectContext
+// calls log via the *slog.Logger found on the calling context.
+func NewLoggingDB(db *sqlx.DB) *LoggingDB {
+	return &LoggingDB{DB: db}
+}
+
+func (l *LoggingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := l.DB.ExecContext(ctx, query, args...)
+	l.log(ctx, "exec", query, time.Since(start), err)
+	return result, err
+}
+
+func (l *LoggingDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := l.DB.GetContext(ctx, dest, query, args...)
+	l.log(ctx, "get", query, time.Since(start), err)
+	return err
+}
+
+func (l *LoggingDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := l.DB.SelectContext(ctx, dest, query, args...)
+	l.log(ctx, "select", query, time.Since(start), err)
+	return err
+}
+
+func (l *LoggingDB) log(ctx context.Context, kind, query string, elapsed time.Duration, err error) {
+	logger := logging.FromContext(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "db query failed", "kind", kind, "query", query, "duration_ms", elapsed.Milliseconds(), "error", err)
+		return
+	}
+	logger.DebugContext(ctx, "db query", "kind", kind, "query", query, "duration_ms", elapsed.Milliseconds())
+}
@@ -0,0 +1,360 @@
+// internal/database/memory.go
+package database
+
+import (
+	"auth-server/internal/auth"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStorage is an in-process Storage implementation backed by plain
+// maps behind a mutex. It exists so handlers can be exercised in tests or
+// run locally without a live Postgres; nothing here is durable or safe to
+// share across processes.
+type MemoryStorage struct {
+	mu sync.Mutex
+
+	nextUserID int
+	usersByID  map[int]*User
+
+	authTokensByUser map[int]*AuthToken
+
+	nextRefreshID int
+	refreshTokens map[int]*RefreshTokenRecord
+
+	authCodes map[string]*AuthCode
+	clients   map[string]*Client
+
+	signingKeys []auth.PersistedKey
+}
+
+// NewMemoryStorage returns an empty MemoryStorage ready to use.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		usersByID:        make(map[int]*User),
+		authTokensByUser: make(map[int]*AuthToken),
+		refreshTokens:    make(map[int]*RefreshTokenRecord),
+		authCodes:        make(map[string]*AuthCode),
+		clients:          make(map[string]*Client),
+	}
+}
+
+func (m *MemoryStorage) Close() error { return nil }
+
+// ProbeHealth always succeeds: there's no network round trip to fail for
+// an in-process map, unlike Database.ProbeHealth's real Postgres write.
+func (m *MemoryStorage) ProbeHealth(ctx context.Context) error { return nil }
+
+// GarbageCollect deletes in-memory auth token entries that expired before
+// now, mirroring Database.GarbageCollect.
+func (m *MemoryStorage) GarbageCollect(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := 0
+	for userID, token := range m.authTokensByUser {
+		if token.ExpiresAt.Before(now) {
+			delete(m.authTokensByUser, userID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Users
+
+func (m *MemoryStorage) CreateUser(ctx context.Context, username, hashedPassword string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextUserID++
+	id := m.nextUserID
+	now := time.Now()
+	m.usersByID[id] = &User{
+		ID:             id,
+		Username:       username,
+		HashedPassword: hashedPassword,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	return id, nil
+}
+
+func (m *MemoryStorage) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.usersByID {
+		if u.Username == username {
+			userCopy := *u
+			return &userCopy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MemoryStorage) GetUserByID(ctx context.Context, id int) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.usersByID[id]
+	if !ok {
+		return nil, nil
+	}
+	userCopy := *u
+	return &userCopy, nil
+}
+
+func (m *MemoryStorage) UpdateLastLogin(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.usersByID[userID]; ok {
+		now := time.Now()
+		u.LastLoginAt = &now
+	}
+	return nil
+}
+
+// Auth tokens
+
+func (m *MemoryStorage) SaveAuthTokens(ctx context.Context, userID int, tokens AuthToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens.UserID = userID
+	tokens.CreatedAt = time.Now()
+	m.authTokensByUser[userID] = &tokens
+	return nil
+}
+
+func (m *MemoryStorage) GetAuthTokensByAccessToken(ctx context.Context, accessToken string) (*AuthToken, *User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, t := range m.authTokensByUser {
+		if t.AccessToken == accessToken {
+			u, ok := m.usersByID[userID]
+			if !ok {
+				return nil, nil, nil
+			}
+			tCopy, uCopy := *t, *u
+			return &tCopy, &uCopy, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func (m *MemoryStorage) GetAuthTokensByRefreshToken(ctx context.Context, refreshToken string) (*AuthToken, *User, error) {
+	m.mu.Lock()
+	record := m.findRefreshTokenLocked(refreshToken)
+	m.mu.Unlock()
+
+	if record == nil {
+		return nil, nil, nil
+	}
+	user, err := m.GetUserByID(ctx, record.UserID)
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+	return &AuthToken{
+		ID:           record.ID,
+		UserID:       record.UserID,
+		RefreshToken: refreshToken,
+		ExpiresAt:    record.ExpiresAt,
+		CreatedAt:    record.CreatedAt,
+	}, user, nil
+}
+
+func (m *MemoryStorage) DeleteAuthTokens(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.authTokensByUser, userID)
+	return nil
+}
+
+// Authorization codes
+
+func (m *MemoryStorage) SaveAuthCode(ctx context.Context, code AuthCode) error {
+	if code.ClientID == "" || code.RedirectURI == "" {
+		return fmt.Errorf("failed to save auth code: client_id and redirect_uri are required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code.CreatedAt = time.Now()
+	m.authCodes[code.Code] = &code
+	return nil
+}
+
+func (m *MemoryStorage) GetAuthCode(ctx context.Context, code string) (*AuthCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.authCodes[code]
+	if !ok {
+		return nil, nil
+	}
+	codeCopy := *c
+	return &codeCopy, nil
+}
+
+func (m *MemoryStorage) DeleteAuthCode(ctx context.Context, code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.authCodes, code)
+	return nil
+}
+
+// GetClientByID looks up a registered client. Clients must be seeded via
+// CreateClient since there's no migration runner for in-memory storage.
+func (m *MemoryStorage) GetClientByID(ctx context.Context, clientID string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.clients[clientID]
+	if !ok {
+		return nil, nil
+	}
+	clientCopy := *c
+	return &clientCopy, nil
+}
+
+// CreateClient seeds a client directly, standing in for the clients table
+// a Postgres deployment would populate via "server clients create".
+func (m *MemoryStorage) CreateClient(ctx context.Context, client Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client.CreatedAt = time.Now()
+	m.clients[client.ClientID] = &client
+	return nil
+}
+
+// Refresh token rotation families
+
+func (m *MemoryStorage) CreateRefreshTokenFamily(ctx context.Context, userID int, rawToken string, expiresAt time.Time) error {
+	familyID, err := auth.GenerateToken(16)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextRefreshID++
+	m.refreshTokens[m.nextRefreshID] = &RefreshTokenRecord{
+		ID:        m.nextRefreshID,
+		FamilyID:  familyID,
+		UserID:    userID,
+		TokenHash: auth.HashToken(rawToken),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	return nil
+}
+
+func (m *MemoryStorage) GetRefreshToken(ctx context.Context, rawToken string) (*RefreshTokenRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record := m.findRefreshTokenLocked(rawToken)
+	if record == nil {
+		return nil, nil
+	}
+	recordCopy := *record
+	return &recordCopy, nil
+}
+
+// findRefreshTokenLocked requires m.mu to already be held.
+func (m *MemoryStorage) findRefreshTokenLocked(rawToken string) *RefreshTokenRecord {
+	hash := auth.HashToken(rawToken)
+	for _, r := range m.refreshTokens {
+		if r.TokenHash == hash {
+			return r
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStorage) RotateRefreshToken(ctx context.Context, record *RefreshTokenRecord, newRawToken string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.refreshTokens[record.ID]; ok {
+		now := time.Now()
+		existing.UsedAt = &now
+	}
+
+	m.nextRefreshID++
+	m.refreshTokens[m.nextRefreshID] = &RefreshTokenRecord{
+		ID:         m.nextRefreshID,
+		FamilyID:   record.FamilyID,
+		UserID:     record.UserID,
+		TokenHash:  auth.HashToken(newRawToken),
+		PreviousID: &record.ID,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  time.Now(),
+	}
+	return nil
+}
+
+func (m *MemoryStorage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.refreshTokens {
+		if r.FamilyID == familyID {
+			r.Revoked = true
+		}
+	}
+	return nil
+}
+
+// CountActiveUsersSince counts distinct users with a token issued at or
+// after since, mirroring Database.CountActiveUsersSince's created_at
+// proxy for activity.
+func (m *MemoryStorage) CountActiveUsersSince(ctx context.Context, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, t := range m.authTokensByUser {
+		if !t.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Signing keys
+
+func (m *MemoryStorage) SaveSigningKey(ctx context.Context, key auth.PersistedKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.signingKeys {
+		if k.Kid == key.Kid {
+			return nil
+		}
+	}
+	m.signingKeys = append(m.signingKeys, key)
+	return nil
+}
+
+func (m *MemoryStorage) LoadSigningKeys(ctx context.Context) ([]auth.PersistedKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]auth.PersistedKey, len(m.signingKeys))
+	copy(keys, m.signingKeys)
+	return keys, nil
+}
+
+var _ Storage = (*MemoryStorage)(nil)
+var _ auth.KeyPersister = (*MemoryStorage)(nil)
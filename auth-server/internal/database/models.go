@@ -6,11 +6,12 @@ import (
 )
 
 type User struct {
-	ID             int       `db:"id"`
-	Username       string    `db:"username"`
-	HashedPassword string    `db:"hashed_password"`
-	CreatedAt      time.Time `db:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at"`
+	ID             int        `db:"id"`
+	Username       string     `db:"username"`
+	HashedPassword string     `db:"hashed_password"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+	LastLoginAt    *time.Time `db:"last_login_at"`
 }
 
 type AuthToken struct {
@@ -21,4 +22,54 @@ type AuthToken struct {
 	CSRFToken    string    `db:"csrf_token"`
 	ExpiresAt    time.Time `db:"expires_at"`
 	CreatedAt    time.Time `db:"created_at"`
+}
+
+// RefreshTokenRecord is one link in a refresh token's rotation chain. Every
+// successful /refresh retires the presented row (UsedAt) and chains a
+// successor into the same FamilyID via PreviousID; a row whose UsedAt is
+// already set being presented again means the token was stolen, and the
+// whole family should be revoked.
+type RefreshTokenRecord struct {
+	ID         int        `db:"id"`
+	FamilyID   string     `db:"family_id"`
+	UserID     int        `db:"user_id"`
+	TokenHash  string     `db:"token_hash"`
+	PreviousID *int       `db:"previous_id"`
+	UsedAt     *time.Time `db:"used_at"`
+	Revoked    bool       `db:"revoked"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// AuthCode is a short-lived authorization code issued by /authorize and
+// redeemed at /token as part of the PKCE-protected authorization code grant.
+type AuthCode struct {
+	Code                string    `db:"code"`
+	UserID              int       `db:"user_id"`
+	ClientID            string    `db:"client_id"`
+	RedirectURI         string    `db:"redirect_uri"`
+	CodeChallenge       string    `db:"code_challenge"`
+	CodeChallengeMethod string    `db:"code_challenge_method"`
+	ExpiresAt           time.Time `db:"expires_at"`
+	CreatedAt           time.Time `db:"created_at"`
+}
+
+// Client is a registered relying party allowed to use the authorization
+// code grant. RedirectURI is the single callback URL it was registered
+// with; /authorize rejects any request naming a different one.
+type Client struct {
+	ClientID    string    `db:"client_id"`
+	Name        string    `db:"name"`
+	RedirectURI string    `db:"redirect_uri"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// Session is a cookie-based login session row, used by the Postgres-backed
+// implementation of session.Store in internal/session.
+type Session struct {
+	ID        string    `db:"id"`
+	UserID    int       `db:"user_id"`
+	CSRFToken string    `db:"csrf_token"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
 }
\ No newline at end of file
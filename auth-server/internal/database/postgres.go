@@ -2,6 +2,7 @@
 package database
 
 import (
+	"auth-server/internal/auth"
 	"auth-server/internal/util"
 	"context"
 	"database/sql"
@@ -14,11 +15,11 @@ import (
 )
 
 type Database struct {
-	DB *sqlx.DB
+	DB *LoggingDB
 }
 
 func NewDatabase(connectionString string) (*Database, error) {
-    defer util.Trace()() 
+    defer util.Trace(context.Background())()
 	db, err := sqlx.Connect("postgres", connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -34,16 +35,47 @@ func NewDatabase(connectionString string) (*Database, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Database{DB: db}, nil
+	return &Database{DB: NewLoggingDB(db)}, nil
 }
 
 func (d *Database) Close() error {
 	return d.DB.Close()
 }
 
+// GarbageCollect deletes auth_tokens rows that expired before now, so a
+// background loop can keep the table from growing unbounded without
+// affecting still-live tokens.
+func (d *Database) GarbageCollect(ctx context.Context, now time.Time) (int, error) {
+    defer util.Trace(ctx)()
+	result, err := d.DB.ExecContext(ctx, "DELETE FROM auth_tokens WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to garbage collect auth tokens: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count garbage collected auth tokens: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// CountActiveUsersSince returns the number of distinct users who were
+// issued an auth token at or after since, for the auth_active_users
+// business metric. This is a proxy for actual activity, not a genuine
+// last-used timestamp: a user who logged in once and never came back
+// still counts until since passes their token's issuance time.
+func (d *Database) CountActiveUsersSince(ctx context.Context, since time.Time) (int, error) {
+    defer util.Trace(ctx)()
+	var count int
+	err := d.DB.GetContext(ctx, &count, "SELECT COUNT(DISTINCT user_id) FROM auth_tokens WHERE created_at >= $1", since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active users: %w", err)
+	}
+	return count, nil
+}
+
 // User methods
 func (d *Database) CreateUser(ctx context.Context, username, hashedPassword string) (int, error) {
-    defer util.Trace()() 
+    defer util.Trace(ctx)() 
 	query := `
 		INSERT INTO users (username, hashed_password)
 		VALUES ($1, $2)
@@ -58,9 +90,9 @@ func (d *Database) CreateUser(ctx context.Context, username, hashedPassword stri
 }
 
 func (d *Database) GetUserByUsername(ctx context.Context, username string) (*User, error) {
-    defer util.Trace()() 
+    defer util.Trace(ctx)()
 	query := `
-		SELECT id, username, hashed_password, created_at, updated_at
+		SELECT id, username, hashed_password, created_at, updated_at, last_login_at
 		FROM users
 		WHERE username = $1
 	`
@@ -75,9 +107,38 @@ func (d *Database) GetUserByUsername(ctx context.Context, username string) (*Use
 	return &user, nil
 }
 
+func (d *Database) GetUserByID(ctx context.Context, id int) (*User, error) {
+    defer util.Trace(ctx)()
+	query := `
+		SELECT id, username, hashed_password, created_at, updated_at, last_login_at
+		FROM users
+		WHERE id = $1
+	`
+	var user User
+	err := d.DB.GetContext(ctx, &user, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // User not found
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// UpdateLastLogin stamps last_login_at with the current time, called on
+// every successful /login so GET /user can report it.
+func (d *Database) UpdateLastLogin(ctx context.Context, userID int) error {
+    defer util.Trace(ctx)()
+	_, err := d.DB.ExecContext(ctx, "UPDATE users SET last_login_at = now() WHERE id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to update last login: %w", err)
+	}
+	return nil
+}
+
 // Auth token methods
 func (d *Database) SaveAuthTokens(ctx context.Context, userID int, tokens AuthToken) error {
-    defer util.Trace()() 
+    defer util.Trace(ctx)() 
 	// First delete any existing tokens for this user
 	_, err := d.DB.ExecContext(ctx, "DELETE FROM auth_tokens WHERE user_id = $1", userID)
 	if err != nil {
@@ -96,11 +157,11 @@ func (d *Database) SaveAuthTokens(ctx context.Context, userID int, tokens AuthTo
 }
 
 func (d *Database) GetAuthTokensByAccessToken(ctx context.Context, accessToken string) (*AuthToken, *User, error) {
-    defer util.Trace()() 
+    defer util.Trace(ctx)() 
     query := `
         SELECT 
             t.id, t.user_id, t.access_token, t.refresh_token, t.csrf_token, t.expires_at, t.created_at,
-            u.id as user_id, u.username, u.hashed_password, u.created_at as user_created_at, u.updated_at as user_updated_at
+            u.id as user_id, u.username, u.hashed_password, u.created_at as user_created_at, u.updated_at as user_updated_at, u.last_login_at
         FROM auth_tokens t
         JOIN users u ON t.user_id = u.id
         WHERE t.access_token = $1
@@ -119,6 +180,7 @@ func (d *Database) GetAuthTokensByAccessToken(ctx context.Context, accessToken s
         HashedPassword string    `db:"hashed_password"`
         UserCreatedAt  time.Time `db:"user_created_at"`
         UserUpdatedAt  time.Time `db:"user_updated_at"`
+        UserLastLoginAt *time.Time `db:"last_login_at"`
     }
     
     var result JoinResult
@@ -146,72 +208,341 @@ func (d *Database) GetAuthTokensByAccessToken(ctx context.Context, accessToken s
         HashedPassword: result.HashedPassword,
         CreatedAt:      result.UserCreatedAt,
         UpdatedAt:      result.UserUpdatedAt,
+        LastLoginAt:    result.UserLastLoginAt,
     }
     
     return token, user, nil
 }
 
+// GetAuthTokensByRefreshToken resolves refreshToken's owner via the
+// refresh_tokens rotation ledger rather than auth_tokens, since auth_tokens
+// only ever holds the latest token for a user and would lose track of a
+// token as soon as it rotated.
 func (d *Database) GetAuthTokensByRefreshToken(ctx context.Context, refreshToken string) (*AuthToken, *User, error) {
-    defer util.Trace()() 
-    query := `
-        SELECT 
-            t.id, t.user_id, t.access_token, t.refresh_token, t.csrf_token, t.expires_at, t.created_at,
-            u.id as user_id, u.username, u.hashed_password, u.created_at as user_created_at, u.updated_at as user_updated_at
-        FROM auth_tokens t
-        JOIN users u ON t.user_id = u.id
-        WHERE t.refresh_token = $1
-    `
-    
-    type JoinResult struct {
-        ID           int       `db:"id"`
-        UserID       int       `db:"user_id"`
-        AccessToken  string    `db:"access_token"`
-        RefreshToken string    `db:"refresh_token"`
-        CSRFToken    string    `db:"csrf_token"`
-        ExpiresAt    time.Time `db:"expires_at"`
-        CreatedAt    time.Time `db:"created_at"`
-        
-        Username       string    `db:"username"`
-        HashedPassword string    `db:"hashed_password"`
-        UserCreatedAt  time.Time `db:"user_created_at"`
-        UserUpdatedAt  time.Time `db:"user_updated_at"`
-    }
-    
-    var result JoinResult
-    err := d.DB.GetContext(ctx, &result, query, refreshToken)
-    if err != nil {
-        if errors.Is(err, sql.ErrNoRows) {
-            return nil, nil, nil // Not found
-        }
-        return nil, nil, fmt.Errorf("failed to query auth tokens: %w", err)
-    }
-    
-    token := &AuthToken{
-        ID:           result.ID,
-        UserID:       result.UserID,
-        AccessToken:  result.AccessToken,
-        RefreshToken: result.RefreshToken,
-        CSRFToken:    result.CSRFToken,
-        ExpiresAt:    result.ExpiresAt,
-        CreatedAt:    result.CreatedAt,
-    }
-    
-    user := &User{
-        ID:             result.UserID,
-        Username:       result.Username,
-        HashedPassword: result.HashedPassword,
-        CreatedAt:      result.UserCreatedAt,
-        UpdatedAt:      result.UserUpdatedAt,
-    }
-    
-    return token, user, nil
+    defer util.Trace(ctx)()
+	record, err := d.GetRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record == nil {
+		return nil, nil, nil
+	}
+
+	user, err := d.GetUserByID(ctx, record.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, nil
+	}
+
+	token := &AuthToken{
+		ID:           record.ID,
+		UserID:       record.UserID,
+		RefreshToken: refreshToken,
+		ExpiresAt:    record.ExpiresAt,
+		CreatedAt:    record.CreatedAt,
+	}
+	return token, user, nil
 }
 
 func (d *Database) DeleteAuthTokens(ctx context.Context, userID int) error {
-    defer util.Trace()() 
+    defer util.Trace(ctx)()
 	_, err := d.DB.ExecContext(ctx, "DELETE FROM auth_tokens WHERE user_id = $1", userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete auth tokens: %w", err)
 	}
 	return nil
+}
+
+// Authorization code methods
+
+func (d *Database) SaveAuthCode(ctx context.Context, code AuthCode) error {
+    defer util.Trace(ctx)()
+	// client_id/redirect_uri are nullable at the schema level only because
+	// migration 0010 added them to a table with pre-existing rows; every
+	// new row still requires both, enforced here instead.
+	if code.ClientID == "" || code.RedirectURI == "" {
+		return fmt.Errorf("failed to save auth code: client_id and redirect_uri are required")
+	}
+	query := `
+		INSERT INTO auth_codes (code, user_id, client_id, redirect_uri, code_challenge, code_challenge_method, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := d.DB.ExecContext(ctx, query, code.Code, code.UserID, code.ClientID, code.RedirectURI, code.CodeChallenge, code.CodeChallengeMethod, code.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save auth code: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetAuthCode(ctx context.Context, code string) (*AuthCode, error) {
+    defer util.Trace(ctx)()
+	query := `
+		SELECT code, user_id, client_id, redirect_uri, code_challenge, code_challenge_method, expires_at, created_at
+		FROM auth_codes
+		WHERE code = $1
+	`
+	var result AuthCode
+	err := d.DB.GetContext(ctx, &result, query, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get auth code: %w", err)
+	}
+	return &result, nil
+}
+
+// DeleteAuthCode removes a code once it has been redeemed so it cannot be replayed.
+func (d *Database) DeleteAuthCode(ctx context.Context, code string) error {
+    defer util.Trace(ctx)()
+	_, err := d.DB.ExecContext(ctx, "DELETE FROM auth_codes WHERE code = $1", code)
+	if err != nil {
+		return fmt.Errorf("failed to delete auth code: %w", err)
+	}
+	return nil
+}
+
+// GetClientByID looks up a registered relying party by its client_id.
+func (d *Database) GetClientByID(ctx context.Context, clientID string) (*Client, error) {
+    defer util.Trace(ctx)()
+	query := `
+		SELECT client_id, name, redirect_uri, created_at
+		FROM clients
+		WHERE client_id = $1
+	`
+	var result Client
+	if err := d.DB.GetContext(ctx, &result, query, clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	return &result, nil
+}
+
+// CreateClient registers a relying party so it can be looked up by
+// GetClientByID during the authorization code grant. Called from the
+// "server clients create" CLI subcommand, since nothing else in this tree
+// populates the clients table.
+func (d *Database) CreateClient(ctx context.Context, client Client) error {
+    defer util.Trace(ctx)()
+	query := `
+		INSERT INTO clients (client_id, name, redirect_uri)
+		VALUES ($1, $2, $3)
+	`
+	_, err := d.DB.ExecContext(ctx, query, client.ClientID, client.Name, client.RedirectURI)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	return nil
+}
+
+// Session methods
+
+// CreateSession inserts a new cookie-session row and returns it with a
+// freshly generated opaque session id.
+func (d *Database) CreateSession(ctx context.Context, userID int, csrfToken string, expiresAt time.Time) (*Session, error) {
+    defer util.Trace(ctx)()
+	id, err := auth.GenerateToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	query := `
+		INSERT INTO sessions (id, user_id, csrf_token, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := d.DB.ExecContext(ctx, query, id, userID, csrfToken, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &Session{ID: id, UserID: userID, CSRFToken: csrfToken, ExpiresAt: expiresAt}, nil
+}
+
+func (d *Database) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+    defer util.Trace(ctx)()
+	query := `
+		SELECT id, user_id, csrf_token, expires_at, created_at
+		FROM sessions
+		WHERE id = $1
+	`
+	var session Session
+	err := d.DB.GetContext(ctx, &session, query, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &session, nil
+}
+
+func (d *Database) DeleteSession(ctx context.Context, sessionID string) error {
+    defer util.Trace(ctx)()
+	_, err := d.DB.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// healthProbeTTL bounds how long a throwaway health_probes row may live in
+// case the delete in ProbeHealth never runs (e.g. the process is killed
+// mid-probe); a periodic cleanup can safely sweep rows past this age.
+const healthProbeTTL = time.Minute
+
+// ProbeHealth exercises the storage layer with a real round trip: it
+// inserts a throwaway row into health_probes and immediately deletes it,
+// so callers (see api.HealthChecker) can measure genuine write latency
+// rather than just pinging the connection.
+func (d *Database) ProbeHealth(ctx context.Context) error {
+    defer util.Trace(ctx)()
+	id := fmt.Sprintf("%d", time.Now().UnixNano())
+	expiresAt := time.Now().Add(healthProbeTTL)
+
+	_, err := d.DB.ExecContext(ctx, "INSERT INTO health_probes (id, expires_at) VALUES ($1, $2)", id, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert health probe: %w", err)
+	}
+
+	_, err = d.DB.ExecContext(ctx, "DELETE FROM health_probes WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete health probe: %w", err)
+	}
+	return nil
+}
+
+// Signing keys
+//
+// These implement auth.KeyPersister so an api.Server can build its
+// auth.KeyStore via auth.NewPersistedKeyStore, surviving process restarts.
+
+func (d *Database) SaveSigningKey(ctx context.Context, key auth.PersistedKey) error {
+    defer util.Trace(ctx)()
+	query := `
+		INSERT INTO signing_keys (kid, private_key_pem, not_before, next_rotation)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (kid) DO NOTHING
+	`
+	if _, err := d.DB.ExecContext(ctx, query, key.Kid, key.PrivateKeyPEM, key.NotBefore, key.NextRotation); err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) LoadSigningKeys(ctx context.Context) ([]auth.PersistedKey, error) {
+    defer util.Trace(ctx)()
+	query := `
+		SELECT kid, private_key_pem, not_before, next_rotation
+		FROM signing_keys
+		ORDER BY not_before ASC
+	`
+	type row struct {
+		Kid           string    `db:"kid"`
+		PrivateKeyPEM string    `db:"private_key_pem"`
+		NotBefore     time.Time `db:"not_before"`
+		NextRotation  time.Time `db:"next_rotation"`
+	}
+	var rows []row
+	if err := d.DB.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	keys := make([]auth.PersistedKey, 0, len(rows))
+	for _, r := range rows {
+		keys = append(keys, auth.PersistedKey{
+			Kid:           r.Kid,
+			PrivateKeyPEM: r.PrivateKeyPEM,
+			NotBefore:     r.NotBefore,
+			NextRotation:  r.NextRotation,
+		})
+	}
+	return keys, nil
+}
+
+// Refresh token rotation
+//
+// Every refresh token belongs to a family: the row issued at login or
+// authorization code exchange roots it, and each /refresh retires the
+// presented row and chains a successor into the same family. A retired row
+// being presented again means the token was stolen, so RevokeRefreshTokenFamily
+// revokes the family rather than just that one row.
+
+// CreateRefreshTokenFamily roots a new rotation family at rawToken.
+func (d *Database) CreateRefreshTokenFamily(ctx context.Context, userID int, rawToken string, expiresAt time.Time) error {
+    defer util.Trace(ctx)()
+	familyID, err := auth.GenerateToken(16)
+	if err != nil {
+		return fmt.Errorf("failed to generate refresh token family id: %w", err)
+	}
+	query := `
+		INSERT INTO refresh_tokens (family_id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := d.DB.ExecContext(ctx, query, familyID, userID, auth.HashToken(rawToken), expiresAt); err != nil {
+		return fmt.Errorf("failed to create refresh token family: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshToken looks up a presented refresh token by its hash.
+func (d *Database) GetRefreshToken(ctx context.Context, rawToken string) (*RefreshTokenRecord, error) {
+    defer util.Trace(ctx)()
+	query := `
+		SELECT id, family_id, user_id, token_hash, previous_id, used_at, revoked, expires_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+	var record RefreshTokenRecord
+	if err := d.DB.GetContext(ctx, &record, query, auth.HashToken(rawToken)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return &record, nil
+}
+
+// RotateRefreshToken retires record and inserts newRawToken as its successor
+// in the same family.
+func (d *Database) RotateRefreshToken(ctx context.Context, record *RefreshTokenRecord, newRawToken string, expiresAt time.Time) error {
+    defer util.Trace(ctx)()
+	// Both statements run in one transaction: without it, a crash or
+	// connection drop between the UPDATE and INSERT would leave the old
+	// token marked used with no replacement row, locking the user out
+	// without ever tripping reuse-detection.
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin refresh token rotation: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = now() WHERE id = $1", record.ID); err != nil {
+		return fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (family_id, user_id, token_hash, previous_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.ExecContext(ctx, query, record.FamilyID, record.UserID, auth.HashToken(newRawToken), record.ID, expiresAt); err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes every token descended from familyID, used
+// when a retired token is replayed because the family may be compromised.
+func (d *Database) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+    defer util.Trace(ctx)()
+	if _, err := d.DB.ExecContext(ctx, "UPDATE refresh_tokens SET revoked = true WHERE family_id = $1", familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,55 @@
+// internal/database/repository.go
+package database
+
+import (
+	"auth-server/internal/auth"
+	"context"
+	"time"
+)
+
+// UserRepository manages the users table. *Database implements this
+// directly; it exists so handlers can depend on the interface rather than
+// the concrete sqlx-backed type.
+type UserRepository interface {
+	CreateUser(ctx context.Context, username, hashedPassword string) (int, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserByID(ctx context.Context, id int) (*User, error)
+	UpdateLastLogin(ctx context.Context, userID int) error
+}
+
+// TokenRepository manages auth_tokens, auth_codes, and refresh token
+// revocation.
+type TokenRepository interface {
+	SaveAuthTokens(ctx context.Context, userID int, tokens AuthToken) error
+	GetAuthTokensByAccessToken(ctx context.Context, accessToken string) (*AuthToken, *User, error)
+	GetAuthTokensByRefreshToken(ctx context.Context, refreshToken string) (*AuthToken, *User, error)
+	DeleteAuthTokens(ctx context.Context, userID int) error
+	SaveAuthCode(ctx context.Context, code AuthCode) error
+	GetAuthCode(ctx context.Context, code string) (*AuthCode, error)
+	DeleteAuthCode(ctx context.Context, code string) error
+	GetClientByID(ctx context.Context, clientID string) (*Client, error)
+	// CreateClient registers a relying party for the authorization code
+	// grant; the "server clients create" CLI subcommand is the expected
+	// way to populate this out of band, the same way an operator would
+	// seed any other reference data.
+	CreateClient(ctx context.Context, client Client) error
+
+	// CreateRefreshTokenFamily starts a new rotation family rooted at
+	// rawToken, called whenever a refresh token is handed out outside of a
+	// rotation (login, authorization code exchange).
+	CreateRefreshTokenFamily(ctx context.Context, userID int, rawToken string, expiresAt time.Time) error
+	// GetRefreshToken looks up a presented refresh token by its hash.
+	GetRefreshToken(ctx context.Context, rawToken string) (*RefreshTokenRecord, error)
+	// RotateRefreshToken retires record and chains newRawToken into the same
+	// family as its successor.
+	RotateRefreshToken(ctx context.Context, record *RefreshTokenRecord, newRawToken string, expiresAt time.Time) error
+	// RevokeRefreshTokenFamily revokes every token descended from familyID,
+	// used when a retired refresh token is presented again (replay).
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+}
+
+var (
+	_ UserRepository     = (*Database)(nil)
+	_ TokenRepository    = (*Database)(nil)
+	_ auth.KeyPersister  = (*Database)(nil)
+)
@@ -0,0 +1,46 @@
+// internal/database/storage.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"auth-server/internal/auth"
+)
+
+// Storage is the full persistence surface api.Handler depends on: the union
+// of UserRepository and TokenRepository plus the lifecycle methods needed
+// to run and garbage-collect a backend. *Database (Postgres) and
+// MemoryStorage both implement it and are selected between at startup by
+// config.StorageBackend (see cmd/server/run.go) — Postgres for production,
+// in-memory for local development or tests without a live database.
+// auth.KeyPersister is embedded so a Storage value can be handed straight to
+// auth.NewPersistedKeyStore without a type assertion back to *Database.
+//
+// A distributed backend (etcd, Redis) could satisfy Storage the same way,
+// but neither is implemented here: both would pull in a new client
+// dependency, and nothing in this tree manages module dependencies to add
+// one.
+type Storage interface {
+	UserRepository
+	TokenRepository
+	auth.KeyPersister
+
+	Close() error
+
+	// GarbageCollect prunes auth_tokens rows that expired before now,
+	// returning how many were deleted so callers (see api.startGCLoop) can
+	// report it as a metric.
+	GarbageCollect(ctx context.Context, now time.Time) (deletedTokens int, err error)
+
+	// ProbeHealth exercises the backend with a real round trip so
+	// api.HealthChecker can report genuine write latency rather than just
+	// a liveness check.
+	ProbeHealth(ctx context.Context) error
+
+	// CountActiveUsersSince returns the number of distinct users who had a
+	// token issued at or after since, backing prometheusmetrics.ActiveUsers.
+	CountActiveUsersSince(ctx context.Context, since time.Time) (int, error)
+}
+
+var _ Storage = (*Database)(nil)
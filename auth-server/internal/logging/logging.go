@@ -0,0 +1,58 @@
+// internal/logging/logging.go
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+type contextKey int
+
+const loggerKey contextKey = 0
+
+// New builds the process-wide *slog.Logger: JSON by default, or a
+// human-readable slog.TextHandler when format is "text". The text output
+// is plain key=value lines; it is not colorized.
+func New(format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
+
+// ParseLevel maps the LOG_LEVEL env value to a slog.Level, defaulting to Info
+// for anything unrecognized.
+func ParseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithLogger returns a context carrying logger, for handlers further down the
+// call chain to retrieve with FromContext.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger stashed by WithLogger, or slog.Default() if
+// the context carries none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,67 @@
+// internal/middleware/cors.go
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORSConfig controls which origins CORS allows. An empty AllowedOrigins
+// means no request is cross-origin-approved, so the decorator becomes a
+// no-op that passes every request, preflight included, straight through.
+type CORSConfig struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+}
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "OPTIONS"}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization"}
+)
+
+// CORS returns a Decorator that answers cross-origin requests per cfg,
+// including short-circuiting preflight OPTIONS requests with a 204. With no
+// AllowedOrigins configured it passes every request straight through.
+func CORS(cfg CORSConfig) Decorator {
+	if len(cfg.AllowedOrigins) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	methods := strings.Join(withDefault(cfg.AllowedMethods, defaultCORSMethods), ", ")
+	headers := strings.Join(withDefault(cfg.AllowedHeaders, defaultCORSHeaders), ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && originAllowed(cfg.AllowedOrigins, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, a := range allowed {
+		if a == "*" || a == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func withDefault(values, fallback []string) []string {
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
@@ -0,0 +1,47 @@
+// internal/middleware/pipeline.go
+package middleware
+
+import "net/http"
+
+// Decorator wraps an http.Handler with additional behavior, the shape used
+// by net/http middleware throughout the Go ecosystem.
+type Decorator func(http.Handler) http.Handler
+
+// Pipeline is an ordered list of Decorators applied to every route
+// registered through it, so cross-cutting concerns (logging, metrics,
+// tracing, rate limiting, auth enforcement) are declared once instead of
+// hand-wired around each handler.
+type Pipeline struct {
+	decorators []Decorator
+}
+
+// New builds a Pipeline from decorators, applied in the order given: the
+// first decorator is outermost, so it sees a request before and a response
+// after every decorator listed after it.
+func New(decorators ...Decorator) *Pipeline {
+	return &Pipeline{decorators: decorators}
+}
+
+// With returns a new Pipeline with extra decorators appended, letting a
+// route layer additional behavior (e.g. rate limiting) on top of the base
+// pipeline without affecting other routes.
+func (p *Pipeline) With(decorators ...Decorator) *Pipeline {
+	combined := make([]Decorator, 0, len(p.decorators)+len(decorators))
+	combined = append(combined, p.decorators...)
+	combined = append(combined, decorators...)
+	return &Pipeline{decorators: combined}
+}
+
+// Then wraps handler with every decorator in the pipeline.
+func (p *Pipeline) Then(handler http.Handler) http.Handler {
+	wrapped := handler
+	for i := len(p.decorators) - 1; i >= 0; i-- {
+		wrapped = p.decorators[i](wrapped)
+	}
+	return wrapped
+}
+
+// Handle registers handler on mux at pattern, wrapped with the pipeline.
+func (p *Pipeline) Handle(mux *http.ServeMux, pattern string, handler http.HandlerFunc) {
+	mux.Handle(pattern, p.Then(handler))
+}
@@ -0,0 +1,22 @@
+// internal/middleware/recover.go
+package middleware
+
+import (
+	"net/http"
+
+	"auth-server/internal/logging"
+)
+
+// Recover converts a panic anywhere further down the chain into a 500
+// response instead of crashing the process, logging the recovered value.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logging.FromContext(r.Context()).Error("panic recovered", "panic", rec)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+// internal/middleware/requestid.go
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"auth-server/internal/auth"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// RequestID reuses an inbound X-Request-ID header if the caller supplied
+// one, otherwise generates one, stores it in the request context, and
+// echoes it back on the response so callers can correlate logs across
+// services.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			generated, err := auth.GenerateToken(16)
+			if err != nil {
+				generated = "unknown"
+			}
+			id = generated
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestIDFromContext returns the request ID stashed by RequestID, or ""
+// if that decorator hasn't run.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
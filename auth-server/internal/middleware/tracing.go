@@ -0,0 +1,60 @@
+// internal/middleware/tracing.go
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"auth-server/internal/auth"
+)
+
+type spanContextKey int
+
+const spanIDKey spanContextKey = iota
+
+// traceparentRE matches the trace-id field of a W3C Trace Context
+// traceparent header (https://www.w3.org/TR/trace-context/#traceparent-header):
+// a 2-hex-digit version (any value, not only "00"), a 32-hex-digit trace
+// ID, a 16-hex-digit parent ID, and 2-hex-digit flags, each hyphen-separated.
+var traceparentRE = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// Tracing stamps each request with a span ID and stores it in the request
+// context so handlers and metrics can correlate work done for one request,
+// the same shape an OpenTelemetry/opentracing HTTP server span would fill
+// in; this tree has no dependency manifest to pull in either SDK, so this
+// is the propagate-an-ID-through-context stand-in a real tracer would slot
+// into later. It reuses the trace ID from an inbound traceparent header
+// when the caller supplied one, the same way RequestID reuses an inbound
+// X-Request-ID, so a request already traced upstream keeps one ID across
+// services instead of fragmenting into a new span here.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanID := traceIDFromTraceparent(r.Header.Get("traceparent"))
+		if spanID == "" {
+			generated, err := auth.GenerateToken(8)
+			if err != nil {
+				generated = "unknown"
+			}
+			spanID = generated
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), spanIDKey, spanID)))
+	})
+}
+
+// traceIDFromTraceparent extracts the trace-id field from a W3C traceparent
+// header value, or "" if header is empty or malformed.
+func traceIDFromTraceparent(header string) string {
+	matches := traceparentRE.FindStringSubmatch(header)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+// SpanIDFromContext returns the span ID stamped by Tracing, or "" if that
+// decorator hasn't run.
+func SpanIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(spanIDKey).(string)
+	return id
+}
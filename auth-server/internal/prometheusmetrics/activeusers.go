@@ -0,0 +1,52 @@
+// internal/prometheusmetrics/activeusers.go
+package prometheusmetrics
+
+import (
+	"context"
+	"time"
+
+	"auth-server/internal/database"
+	"auth-server/internal/logging"
+)
+
+// activeUserWindow is how recently a token must have been issued for its
+// owner to count toward ActiveUsers. This is an approximation of activity
+// by issuance time (auth_tokens.created_at), not genuine last-use — this
+// tree has no last-activity column to query instead — so a user who logged
+// in once and made no further requests still counts as active for the
+// whole window, while one still making requests with a token issued
+// before the window does not. A /refresh re-issues the user's auth_tokens
+// row, so refreshing does count.
+const activeUserWindow = time.Hour
+
+// activeUserRefreshInterval controls how often ActiveUsers is recomputed.
+const activeUserRefreshInterval = time.Minute
+
+// StartActiveUserLoop runs one synchronous refresh of ActiveUsers, then a
+// background goroutine that repeats every activeUserRefreshInterval until
+// ctx is cancelled, mirroring api.newHealthChecker's probe-then-poll shape.
+func (m *Metrics) StartActiveUserLoop(ctx context.Context, db database.Storage) {
+	m.refreshActiveUsers(ctx, db)
+
+	go func() {
+		ticker := time.NewTicker(activeUserRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.refreshActiveUsers(ctx, db)
+			}
+		}
+	}()
+}
+
+func (m *Metrics) refreshActiveUsers(ctx context.Context, db database.Storage) {
+	count, err := db.CountActiveUsersSince(ctx, time.Now().Add(-activeUserWindow))
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to refresh active user count", "error", err)
+		return
+	}
+	m.ActiveUsers.Set(float64(count))
+}
@@ -0,0 +1,82 @@
+// internal/prometheusmetrics/metrics.go
+package prometheusmetrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Login result labels for Metrics.LoginsTotal.
+const (
+	LoginResultSuccess = "success"
+	LoginResultFailure = "failure"
+	LoginResultLocked  = "locked"
+)
+
+// Token type labels for Metrics.TokensIssued.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Metrics exposes auth-domain business metrics — active users, logins,
+// registrations, token refreshes and issuance — distinct from the
+// HTTP-layer request metrics in api.Metrics, so operators get an SLO
+// dashboard about authentication itself rather than just transport stats.
+type Metrics struct {
+	ActiveUsers    prometheus.Gauge
+	LoginsTotal    *prometheus.CounterVec
+	Registrations  prometheus.Counter
+	Logouts        prometheus.Counter
+	TokenRefreshes prometheus.Counter
+	TokensIssued   *prometheus.CounterVec
+}
+
+// New builds and registers the business metrics with Prometheus.
+func New() *Metrics {
+	m := &Metrics{
+		ActiveUsers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "auth_active_users",
+			Help: "Number of users with a token issued within the last hour",
+		}),
+		LoginsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "auth_logins_total",
+				Help: "Total number of login attempts by result",
+			},
+			[]string{"result"},
+		),
+		Registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_registrations_total",
+			Help: "Total number of user registrations",
+		}),
+		Logouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_logouts_total",
+			Help: "Total number of logouts",
+		}),
+		TokenRefreshes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "auth_token_refreshes_total",
+			Help: "Total number of refresh token exchanges",
+		}),
+		TokensIssued: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "auth_tokens_issued_total",
+				Help: "Total number of tokens issued by type",
+			},
+			[]string{"type"},
+		),
+	}
+
+	prometheus.MustRegister(m.ActiveUsers)
+	prometheus.MustRegister(m.LoginsTotal)
+	prometheus.MustRegister(m.Registrations)
+	prometheus.MustRegister(m.Logouts)
+	prometheus.MustRegister(m.TokenRefreshes)
+	prometheus.MustRegister(m.TokensIssued)
+
+	return m
+}
+
+// RecordTokensIssued increments TokensIssued for the access/refresh pair
+// auth.IssueAuthTokens always mints together.
+func (m *Metrics) RecordTokensIssued() {
+	m.TokensIssued.WithLabelValues(TokenTypeAccess).Inc()
+	m.TokensIssued.WithLabelValues(TokenTypeRefresh).Inc()
+}
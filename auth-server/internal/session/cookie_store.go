@@ -0,0 +1,65 @@
+// internal/session/cookie_store.go
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/securecookie"
+)
+
+// cookieName is the securecookie encoding name; it is unrelated to the
+// actual HTTP cookie name the server sets, which callers choose themselves.
+const cookieName = "session"
+
+// cookiePayload is what CookieStore encodes into the cookie value itself,
+// so no server-side state is needed to validate or load a session.
+type cookiePayload struct {
+	UserID    int
+	CSRFToken string
+	ExpiresAt time.Time
+}
+
+// CookieStore is the development-mode Store: the entire session is signed
+// and encrypted into the cookie value via gorilla/securecookie, so there is
+// nothing to look up or clean up server-side.
+type CookieStore struct {
+	codec securecookie.Codec
+}
+
+// NewCookieStore builds a CookieStore from a securecookie hash/block key
+// pair. Callers that don't pass stable keys (e.g. via securecookie.GenerateRandomKey
+// at startup) will invalidate every outstanding session on restart.
+func NewCookieStore(hashKey, blockKey []byte) *CookieStore {
+	return &CookieStore{codec: securecookie.New(hashKey, blockKey)}
+}
+
+func (s *CookieStore) New(ctx context.Context, userID int, csrfToken string, ttl time.Duration) (*Session, error) {
+	payload := cookiePayload{
+		UserID:    userID,
+		CSRFToken: csrfToken,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	encoded, err := s.codec.Encode(cookieName, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session cookie: %w", err)
+	}
+
+	return &Session{ID: encoded, UserID: userID, CSRFToken: csrfToken, ExpiresAt: payload.ExpiresAt}, nil
+}
+
+func (s *CookieStore) Get(ctx context.Context, sessionID string) (*Session, error) {
+	var payload cookiePayload
+	if err := s.codec.Decode(cookieName, sessionID, &payload); err != nil {
+		return nil, nil // tampered or unrecognized cookie: treat as "no session"
+	}
+	return &Session{ID: sessionID, UserID: payload.UserID, CSRFToken: payload.CSRFToken, ExpiresAt: payload.ExpiresAt}, nil
+}
+
+// Delete is a no-op: CookieStore keeps no server-side state, so there is
+// nothing to delete. Callers must still clear the cookie client-side.
+func (s *CookieStore) Delete(ctx context.Context, sessionID string) error {
+	return nil
+}
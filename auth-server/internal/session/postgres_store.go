@@ -0,0 +1,41 @@
+// internal/session/postgres_store.go
+package session
+
+import (
+	"context"
+	"time"
+
+	"auth-server/internal/database"
+)
+
+// PostgresStore is the production-mode Store: it persists sessions as rows
+// in the sessions table, reusing the server's existing sqlx connection
+// rather than opening a second pool or external store.
+type PostgresStore struct {
+	db *database.Database
+}
+
+// NewPostgresStore wraps db as a Store.
+func NewPostgresStore(db *database.Database) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) New(ctx context.Context, userID int, csrfToken string, ttl time.Duration) (*Session, error) {
+	row, err := s.db.CreateSession(ctx, userID, csrfToken, time.Now().Add(ttl))
+	if err != nil {
+		return nil, err
+	}
+	return &Session{ID: row.ID, UserID: row.UserID, CSRFToken: row.CSRFToken, ExpiresAt: row.ExpiresAt}, nil
+}
+
+func (s *PostgresStore) Get(ctx context.Context, sessionID string) (*Session, error) {
+	row, err := s.db.GetSession(ctx, sessionID)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return &Session{ID: row.ID, UserID: row.UserID, CSRFToken: row.CSRFToken, ExpiresAt: row.ExpiresAt}, nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, sessionID string) error {
+	return s.db.DeleteSession(ctx, sessionID)
+}
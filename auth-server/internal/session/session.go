@@ -0,0 +1,25 @@
+// internal/session/session.go
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// Session represents an authenticated cookie-based login session.
+type Session struct {
+	ID        string
+	UserID    int
+	CSRFToken string
+	ExpiresAt time.Time
+}
+
+// Store abstracts where session state lives: a stateless securecookie
+// payload for local development (CookieStore) or a Postgres-backed row for
+// production (PostgresStore). In both cases Session.ID is exactly the value
+// the caller should send back to the client as the session cookie.
+type Store interface {
+	New(ctx context.Context, userID int, csrfToken string, ttl time.Duration) (*Session, error)
+	Get(ctx context.Context, sessionID string) (*Session, error)
+	Delete(ctx context.Context, sessionID string) error
+}
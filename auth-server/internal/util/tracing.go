@@ -1,47 +1,32 @@
 package util
 
 import (
-	"log"
+	"auth-server/internal/logging"
+	"context"
 	"runtime"
 	"time"
 )
 
-// ANSI color codes
-const (
-	colorReset  = "\033[0m"
-	colorRed    = "\033[31m"
-	colorGreen  = "\033[32m"
-	colorYellow = "\033[33m"
-	colorBlue   = "\033[34m"
-	colorPurple = "\033[35m"
-	colorCyan   = "\033[36m"
-)
-
-// FunctionTracer provides function execution tracing with colored output
-func Trace() func() {
-	// Get function name through runtime reflection
+// Trace logs a debug start/end event pair for the calling function, carrying
+// a func attribute (from runtime reflection) and, on the end event, a
+// duration_ms attribute. The returned func must be deferred by the caller.
+// No ANSI color codes are emitted; formatting is left entirely to the
+// configured slog handler.
+func Trace(ctx context.Context) func() {
 	pc, _, _, ok := runtime.Caller(1)
 	funcName := "unknown"
 	if ok {
 		funcName = runtime.FuncForPC(pc).Name()
 	}
-	
-	log.Printf("%s▶ TRACE: Function %s started%s", colorGreen, funcName, colorReset)
+
+	logger := logging.FromContext(ctx)
+	logger.DebugContext(ctx, "trace started", "func", funcName)
 	start := time.Now()
-	
+
 	return func() {
-		elapsed := time.Since(start)
-		// Use different colors based on execution time
-		durationColor := colorGreen
-		if elapsed > 100*time.Millisecond {
-			durationColor = colorYellow
-		}
-		if elapsed > 500*time.Millisecond {
-			durationColor = colorRed
-		}
-		
-		log.Printf("%s◼ TRACE: Function %s ended %s(took %s%v%s)%s", 
-			colorBlue, funcName, colorReset, 
-			durationColor, elapsed, colorReset, colorReset)
+		logger.DebugContext(ctx, "trace ended",
+			"func", funcName,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
 	}
-}
\ No newline at end of file
+}
@@ -1,14 +1,45 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
 )
 
 var ErrAuth = errors.New("Unauthorized")
 
+// signingKey is the RSA key this (single-instance) server signs access tokens
+// with. It lives only in memory, mirroring the pre-JWT opaque tokens it
+// replaces: restarting the process invalidates every outstanding token.
+var signingKey = mustGenerateSigningKey()
+
+func mustGenerateSigningKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate JWT signing key: %v", err))
+	}
+	return key
+}
+
+// accessClaims is the payload of the signed access token JWT.
+type accessClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+}
+
+const tokenIssuer = "microservices-auth"
+
 // AuthTokens represents the authentication tokens
 type AuthTokens struct {
 	AccessToken  string
@@ -17,43 +48,84 @@ type AuthTokens struct {
 	ExpiresAt    time.Time
 }
 
-// Authorize validates access and CSRF tokens from the request
+// issueAccessToken mints an RS256-signed JWT access token for username, valid
+// until expiresAt.
+func issueAccessToken(username string, expiresAt time.Time) (string, error) {
+	jti := generateToken(16)
+	if jti == "" {
+		return "", errors.New("failed to generate jti")
+	}
+
+	claims := accessClaims{
+		Issuer:    tokenIssuer,
+		Subject:   username,
+		Audience:  tokenIssuer,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		ID:        jti,
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: signingKey}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", fmt.Errorf("failed to create signer: %w", err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return jws.CompactSerialize()
+}
+
+// Authorize validates the access token JWT and the CSRF token from the request.
 func Authorize(r *http.Request) error {
 	// Get the access token from the header
 	accessToken := r.Header.Get("X-ACCESS-TOKEN")
 	if accessToken == "" {
 		return ErrAuth
 	}
-	
-	log.Printf("Access Token: %s", accessToken)
-	
+
 	// Get the CSRF token from the header
 	csrfToken := r.Header.Get("X-CSRF-TOKEN")
 	if csrfToken == "" {
 		return ErrAuth
 	}
-	log.Printf("CSRF Token: %s", csrfToken)
-	
-	// Find the user with this access token
-	foundValidUser := false
-	for _, userData := range database {
-		if userData.Tokens.AccessToken == accessToken {
-			// Check if token is expired
-			if time.Now().After(userData.Tokens.ExpiresAt) {
-				return errors.New("access token expired")
-			}
-			
-			// Verify CSRF token
-			if userData.Tokens.CSRFToken == csrfToken {
-				foundValidUser = true
-				break
-			}
-		}
-	}
-	
-	if !foundValidUser {
+
+	jws, err := jose.ParseSigned(accessToken)
+	if err != nil {
+		return ErrAuth
+	}
+
+	payload, err := jws.Verify(&signingKey.PublicKey)
+	if err != nil {
+		return ErrAuth
+	}
+
+	var claims accessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
 		return ErrAuth
 	}
-	
+	if claims.Issuer != tokenIssuer || claims.Audience != tokenIssuer {
+		return ErrAuth
+	}
+	if time.Now().After(time.Unix(claims.ExpiresAt, 0)) {
+		return errors.New("access token expired")
+	}
+
+	// The JWT signature and exp claim are now what authenticate the access
+	// token itself; the map is only consulted for the CSRF double-submit
+	// check, not to re-validate the access token's presence or value.
+	userData, exists := database[claims.Subject]
+	if !exists || userData.Tokens.CSRFToken != csrfToken {
+		return ErrAuth
+	}
+
+	log.Printf("Authorized request for user: %s", claims.Subject)
+
 	return nil
 }
\ No newline at end of file
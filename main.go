@@ -165,8 +165,12 @@ func login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate new tokens
-	tokens := generateAuthTokens()
-	
+	tokens, err := generateAuthTokens(username)
+	if err != nil {
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	// Store tokens in the database
 	user.Tokens = tokens
 	database[username] = user
@@ -32,14 +32,22 @@ func checkPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// generateAuthTokens creates a new set of authentication tokens
-func generateAuthTokens() AuthTokens {
+// generateAuthTokens creates a new set of authentication tokens for username:
+// a signed JWT access token plus opaque refresh and CSRF tokens.
+func generateAuthTokens(username string) (AuthTokens, error) {
+	expiresAt := time.Now().Add(15 * time.Minute) // Access token expires in 15 minutes
+
+	accessToken, err := issueAccessToken(username, expiresAt)
+	if err != nil {
+		return AuthTokens{}, err
+	}
+
 	return AuthTokens{
-		AccessToken:  generateToken(32),
+		AccessToken:  accessToken,
 		RefreshToken: generateToken(64),
 		CSRFToken:    generateToken(32),
-		ExpiresAt:    time.Now().Add(15 * time.Minute), // Access token expires in 15 minutes
-	}
+		ExpiresAt:    expiresAt,
+	}, nil
 }
 
 // refreshAuthTokens generates new access token using a valid refresh token
@@ -48,15 +56,18 @@ func refreshAuthTokens(refreshToken string, username string) (AuthTokens, error)
 	if !exists {
 		return AuthTokens{}, errors.New("user not found")
 	}
-	
+
 	// Use constant-time comparison to prevent timing attacks
 	if subtle.ConstantTimeCompare([]byte(refreshToken), []byte(userData.Tokens.RefreshToken)) != 1 {
 		return AuthTokens{}, errors.New("invalid refresh token")
 	}
-	
+
 	// Generate new tokens but keep the same refresh token
-	newTokens := generateAuthTokens()
+	newTokens, err := generateAuthTokens(username)
+	if err != nil {
+		return AuthTokens{}, err
+	}
 	newTokens.RefreshToken = userData.Tokens.RefreshToken
-	
+
 	return newTokens, nil
 }
\ No newline at end of file